@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-formation/formation"
+	"go-formation/utility"
+	"io/ioutil"
+	"os"
+)
+
+// runDumpAst 输出配置表 formation 行中每个 format(...) 的 json AST，没有指定文件时解析标准输入中的单元格
+func runDumpAst(argSlice []string) int {
+	flagSet := flag.NewFlagSet("dump-ast", flag.ExitOnError)
+	indent := flagSet.Bool("indent", false, "indent json output")
+	projectPath := flagSet.String("project", "", "project config file providing the header layout")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: go-formation dump-ast [-indent] [-project project.json] [file.csv ...]\n")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(argSlice)
+	if err := applyProjectFile(*projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v: %v\n", *projectPath, err)
+		return 2
+	}
+
+	exitCode := 0
+	formationAstSlice := make([]*formation.FormationAst, 0)
+	if flagSet.NArg() == 0 {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: read stdin occurs error: %v\n", err)
+			return 2
+		}
+		f, err := formation.NewFormation("", "", string(content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if f != nil {
+			formationAstSlice = append(formationAstSlice, formation.NewFormationAst(f))
+		}
+	}
+	for _, path := range flagSet.Args() {
+		recordSlice, _, err := readCsvRecordSlice(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v: %v\n", path, err)
+			exitCode = 2
+			continue
+		}
+		formationRow := headerRowIndex(utility.HEADER_FORMATION)
+		if formationRow < 0 || formationRow >= len(recordSlice) {
+			continue
+		}
+		for i, cell := range recordSlice[formationRow] {
+			f, err := formation.NewFormation(tableNameOf(path), fieldNameOf(recordSlice, i), cell)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v: %v\n", path, err)
+				exitCode = 2
+				continue
+			}
+			if f != nil {
+				formationAstSlice = append(formationAstSlice, formation.NewFormationAst(f))
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	if *indent {
+		encoder.SetIndent("", "  ")
+	}
+	if err := encoder.Encode(formationAstSlice); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+	return exitCode
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"go-formation/formation"
+	"go-formation/utility"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// runCheck 导出每个 csv 文件后检查主键唯一、formation 引用的值存在以及 sum、unique 等注解，有错误时返回 1
+func runCheck(argSlice []string) int {
+	flagSet := flag.NewFlagSet("check", flag.ExitOnError)
+	target := flagSet.String("target", "", "only check fields exported to target (server or client)")
+	projectPath := flagSet.String("project", "", "project config file providing the header layout, primary keys and enums")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: go-formation check [-target server|client] [-project project.json] file.csv ...\n")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(argSlice)
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return 2
+	}
+	if err := applyProjectFile(*projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v: %v\n", *projectPath, err)
+		return 2
+	}
+	return checkFiles(flagSet.Args(), *target, os.Stderr)
+}
+
+// checkFiles 检查 pathSlice 中的 csv 文件，警告与错误输出到 w，返回进程退出码
+func checkFiles(pathSlice []string, target string, w io.Writer) int {
+	// 检查结果由返回的错误输出，不再重复输出 Reporter 的诊断信息
+	utility.SetReporter(utility.NewWriterReporter(ioutil.Discard))
+	exitCode := 0
+	gameDataJsonObjectMap := make(map[string]*formation.GameDataJsonObject)
+	fileFormationMap := make(map[string]map[string]string)
+	for _, path := range pathSlice {
+		tableName := tableNameOf(path)
+		gameDataJsonObject, formationMap, err := loadCsvGameData(tableName, path)
+		if err != nil {
+			fmt.Fprintf(w, "Error: %v: %v\n", path, err)
+			exitCode = 2
+			continue
+		}
+		gameDataJsonObjectMap[tableName] = gameDataJsonObject
+		fileFormationMap[tableName] = formationMap
+	}
+
+	checkErrorSlice, warningSlice := formation.CheckGameData(gameDataJsonObjectMap, fileFormationMap, &formation.CheckOption{Target: target, Checker: formation.NewChecker()})
+	for _, warning := range warningSlice {
+		fmt.Fprintf(w, "Warning: %v\n", warning)
+	}
+	for _, checkError := range checkErrorSlice {
+		fmt.Fprintf(w, "Error: %v\n", checkError)
+	}
+	if len(checkErrorSlice) != 0 && exitCode == 0 {
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// loadCsvGameData 按 EXPORT_ALL 导出 csv 文件，json 中的 Export 记录每列的导出目标，-target 据此只检查导出到该目标的列，
+// 导出的 json 通过管道边写边解析，不在内存中保留整个 json
+func loadCsvGameData(tableName, path string) (*formation.GameDataJsonObject, map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+	transReader, err := utility.NewDecodeReader(file, utility.GetDefaultEncoding())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pipeReader, pipeWriter := io.Pipe()
+	var formationMap map[string]string
+	exportErrorChan := make(chan error, 1)
+	go func() {
+		var err error
+		formationMap, err = utility.ProcessCsvAndFormationForTargetsToWriter(map[utility.ExportTarget]io.Writer{utility.EXPORT_ALL: pipeWriter}, tableName, csv.NewReader(transReader), utility.GetDefaultHeaderLayout())
+		pipeWriter.CloseWithError(err)
+		exportErrorChan <- err
+	}()
+	gameDataJsonObject, err := formation.LoadGameDataJsonObject(pipeReader)
+	// 解析提前结束时关闭管道，使导出的 goroutine 不会阻塞在写入上
+	pipeReader.Close()
+	exportErr := <-exportErrorChan
+	if err != nil {
+		return nil, nil, err
+	}
+	if exportErr != nil {
+		return nil, nil, exportErr
+	}
+	return gameDataJsonObject, formationMap, nil
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckTarget(t *testing.T) {
+	dir := t.TempDir()
+	// item_name 只导出到服务器且引用了不存在的 zzz，client_item 只导出到客户端且引用了不存在的 1003
+	itemCsv := "comment,comment\n,\nboth,server\nid,name\nint,string\n1001,a\n1002,b\n"
+	rewardCsv := "comment,comment,comment,comment\n,format(Item.id),format(Item.name),format(Item.id)\nboth,both,server,client\nid,item,item_name,client_item\nint,int,string,int\n1,1001,zzz,1003\n"
+	for name, content := range map[string]string{"Item.csv": itemCsv, "Reward.csv": rewardCsv} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pathSlice := []string{filepath.Join(dir, "Item.csv"), filepath.Join(dir, "Reward.csv")}
+
+	for _, c := range []struct {
+		target           string
+		exitCode         int
+		errorContainText []string
+		errorExcludeText []string
+	}{
+		{"server", 1, []string{"Item.name can not find content zzz"}, []string{"1003"}},
+		{"client", 1, []string{"Item.id can not find content 1003"}, []string{"zzz", "not exported to client"}},
+		{"", 1, []string{"Item.name can not find content zzz", "Item.id can not find content 1003"}, nil},
+	} {
+		buffer := &bytes.Buffer{}
+		if exitCode := checkFiles(pathSlice, c.target, buffer); exitCode != c.exitCode {
+			t.Errorf("check -target %q exit code = %v, expect %v, output %q", c.target, exitCode, c.exitCode, buffer.String())
+		}
+		for _, text := range c.errorContainText {
+			if !strings.Contains(buffer.String(), text) {
+				t.Errorf("check -target %q output %q, expect contain %v", c.target, buffer.String(), text)
+			}
+		}
+		for _, text := range c.errorExcludeText {
+			if strings.Contains(buffer.String(), text) {
+				t.Errorf("check -target %q output %q, expect not contain %v", c.target, buffer.String(), text)
+			}
+		}
+	}
+
+	if exitCode := runCheck(append([]string{"-target", "client"}, pathSlice[:1]...)); exitCode != 0 {
+		t.Errorf("runCheck -target client %v exit code = %v, expect 0", pathSlice[:1], exitCode)
+	}
+	if exitCode := runCheck(append([]string{"-target", "client"}, pathSlice...)); exitCode != 1 {
+		t.Errorf("runCheck -target client %v exit code = %v, expect 1", pathSlice, exitCode)
+	}
+}
+
+func TestLoadCsvGameData(t *testing.T) {
+	dir := t.TempDir()
+	for _, c := range []struct {
+		name        string
+		content     string
+		expectRow   int
+		errorText   string
+		expectError bool
+	}{
+		{"valid", "comment,comment\n,rules(sum-per-group=30)\nserver,server\nid,weight\nint,int\n1,10\n2,20\n", 2, "", false},
+		{"rule failed", "comment,comment\n,rules(sum-per-group=100)\nserver,server\nid,weight\nint,int\n1,10\n2,20\n", 0, "sum 30 is not equal 100", true},
+		{"invalid cell", "comment,comment\n,\nserver,server\nid,weight\nint,int\n1,abc\n", 0, "row 6", true},
+	} {
+		path := filepath.Join(dir, "Pool.csv")
+		if err := ioutil.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		gameDataJsonObject, formationMap, err := loadCsvGameData("Pool", path)
+		if c.expectError {
+			if err == nil || !strings.Contains(err.Error(), c.errorText) {
+				t.Errorf("%v: loadCsvGameData error = %v, expect contain %v", c.name, err, c.errorText)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: loadCsvGameData error: %v", c.name, err)
+			continue
+		}
+		if len(gameDataJsonObject.Data) != c.expectRow || gameDataJsonObject.Export == nil || formationMap["weight"] != "rules(sum-per-group=30)" {
+			t.Errorf("%v: loadCsvGameData = %+v, %v", c.name, gameDataJsonObject, formationMap)
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package codegen
+
+import (
+	"fmt"
+	"go-formation/formation"
+	"go-formation/utility"
+	"sort"
+	"strings"
+)
+
+// referenceSlot 描述如何从单元格中取出引用的值：先按 GroupSeparator 分组，再按 SlotSeparator 分槽，
+// SlotCount 为 1 时每个槽都是引用，否则只在槽数量等于 SlotCount 时取 IndexSlice 位置上的槽
+type referenceSlot struct {
+	GroupSeparator string
+	SlotSeparator  string
+	SlotCount      int
+	IndexSlice     []int
+}
+
+type referenceTarget struct {
+	File  string
+	Field string
+}
+
+// reference 表中一个字段对另一张表一个字段的引用，非装饰的 formation 只有一个键为空字符串的分支
+type reference struct {
+	File             string
+	Field            string
+	Target           referenceTarget
+	BranchField      string
+	BranchValueSlice []string
+	BranchSlotMap    map[string]*referenceSlot
+}
+
+func collectReferenceSlot(node formation.Node) map[referenceTarget]*referenceSlot {
+	referenceSlotMap := make(map[referenceTarget]*referenceSlot)
+	switch n := node.(type) {
+	case *formation.FullstopNode:
+		if !n.IsPlaceHolder {
+			referenceSlotMap[referenceTarget{File: n.GetKey(), Field: n.GetValue()}] = &referenceSlot{}
+		}
+	case *formation.CommaNode:
+		for index, subNode := range n.SubNodeSlice {
+			for file, field := range subNode.GetRelateFileFieldMap() {
+				target := referenceTarget{File: file, Field: field}
+				if _, hasTarget := referenceSlotMap[target]; !hasTarget {
+					referenceSlotMap[target] = &referenceSlot{SlotSeparator: ",", SlotCount: len(n.SubNodeSlice)}
+				}
+				referenceSlotMap[target].IndexSlice = append(referenceSlotMap[target].IndexSlice, index)
+			}
+		}
+	case *formation.SemicolonNode:
+		for target, slot := range collectReferenceSlot(n.SubNode) {
+			slot.GroupSeparator = ";"
+			referenceSlotMap[target] = slot
+		}
+	case *formation.ColonNode:
+		return collectReferenceSlot(n.ValueNode)
+	default:
+	}
+	return referenceSlotMap
+}
+
+func collectReference(f *formation.Formation) ([]*reference, error) {
+	referenceMap := make(map[referenceTarget]*reference)
+	getReference := func(target referenceTarget) *reference {
+		if _, hasTarget := referenceMap[target]; !hasTarget {
+			referenceMap[target] = &reference{File: f.File, Field: f.Field, Target: target, BranchSlotMap: make(map[string]*referenceSlot)}
+		}
+		return referenceMap[target]
+	}
+
+	if f.HasDecoration {
+		branchKeyNode := f.DecorationNode.RefKeyFormationNode
+		if branchKeyNode == nil || branchKeyNode.GetKey() != f.File {
+			return nil, fmt.Errorf("%v.%v decoration key must reference a field of %v", f.File, f.Field, f.File)
+		}
+		for branchValue, colonNode := range f.DecorationNode.RefValueSubFormationMap {
+			for target, slot := range collectReferenceSlot(colonNode) {
+				r := getReference(target)
+				r.BranchField = branchKeyNode.GetValue()
+				r.BranchSlotMap[branchValue] = slot
+			}
+		}
+	} else {
+		for target, slot := range collectReferenceSlot(f.FormationNode) {
+			getReference(target).BranchSlotMap[""] = slot
+		}
+	}
+
+	referenceSlice := make([]*reference, 0, len(referenceMap))
+	for _, r := range referenceMap {
+		for branchValue := range r.BranchSlotMap {
+			r.BranchValueSlice = append(r.BranchValueSlice, branchValue)
+		}
+		sort.Strings(r.BranchValueSlice)
+		referenceSlice = append(referenceSlice, r)
+	}
+	sort.Slice(referenceSlice, func(i, j int) bool {
+		if referenceSlice[i].Target.File != referenceSlice[j].Target.File {
+			return referenceSlice[i].Target.File < referenceSlice[j].Target.File
+		}
+		return referenceSlice[i].Target.Field < referenceSlice[j].Target.Field
+	})
+	return referenceSlice, nil
+}
+
+// collectTableReference 解析表中每个字段的 formation，只保留引用了生成器中的表的引用
+func (g *Generator) collectTableReference(table *utility.Table) ([]*reference, error) {
+	referenceSlice := make([]*reference, 0)
+	for _, key := range table.KeySlice {
+		f, err := formation.NewFormation(table.Name, key.Name, table.FormationMap[key.Name])
+		if err != nil {
+			return nil, err
+		}
+		if f == nil {
+			continue
+		}
+		fieldReferenceSlice, err := collectReference(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range fieldReferenceSlice {
+			targetTable := g.getTable(r.Target.File)
+			if targetTable == nil {
+				continue
+			}
+			if !hasKey(targetTable, r.Target.Field) {
+				return nil, fmt.Errorf("%v.%v references %v.%v which does not exist", r.File, r.Field, r.Target.File, r.Target.Field)
+			}
+			if len(r.BranchField) != 0 && !hasKey(table, r.BranchField) {
+				return nil, fmt.Errorf("%v.%v decoration key %v does not exist", r.File, r.Field, r.BranchField)
+			}
+			referenceSlice = append(referenceSlice, r)
+		}
+	}
+	return referenceSlice, nil
+}
+
+func (g *Generator) getTable(name string) *utility.Table {
+	for _, table := range g.TableSlice {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+func hasKey(table *utility.Table, name string) bool {
+	for _, key := range table.KeySlice {
+		if key.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func indexFunctionName(target referenceTarget) string {
+	return fmt.Sprintf("%vBy%v", ToCamelCase(target.File), ToCamelCase(target.Field))
+}
+
+func indexFieldName(target referenceTarget) string {
+	name := indexFunctionName(target)
+	return strings.ToLower(name[:1]) + name[1:]
+}
+
+// generateDB 生成持有所有表的 DB、被引用字段的索引以及 LoadDB
+func (g *Generator) generateDB(builder *strings.Builder, referenceSlice []*reference) {
+	targetSet := make(map[referenceTarget]bool)
+	targetSlice := make([]referenceTarget, 0)
+	for _, r := range referenceSlice {
+		if !targetSet[r.Target] {
+			targetSet[r.Target] = true
+			targetSlice = append(targetSlice, r.Target)
+		}
+	}
+	sort.Slice(targetSlice, func(i, j int) bool {
+		return indexFunctionName(targetSlice[i]) < indexFunctionName(targetSlice[j])
+	})
+
+	fmt.Fprintf(builder, "// DB 持有所有配置表，以及被 formation 引用的字段的索引\n")
+	fmt.Fprintf(builder, "type DB struct {\n")
+	for _, table := range g.TableSlice {
+		fmt.Fprintf(builder, "\t%v []*%v\n", ToCamelCase(table.Name), ToCamelCase(table.Name))
+	}
+	if len(targetSlice) != 0 {
+		fmt.Fprintf(builder, "\n")
+	}
+	for _, target := range targetSlice {
+		fmt.Fprintf(builder, "\t%v map[string][]*%v\n", indexFieldName(target), ToCamelCase(target.File))
+	}
+	fmt.Fprintf(builder, "}\n\n")
+
+	fmt.Fprintf(builder, "// LoadDB 以表名为键加载 Format/Data json，缺少的表为空\n")
+	fmt.Fprintf(builder, "func LoadDB(dataMap map[string][]byte) (*DB, error) {\n")
+	fmt.Fprintf(builder, "\tdb := &DB{}\n")
+	for _, table := range g.TableSlice {
+		structName := ToCamelCase(table.Name)
+		fmt.Fprintf(builder, "\tif data, hasData := dataMap[%q]; hasData {\n", table.Name)
+		fmt.Fprintf(builder, "\t\tvalueSlice, err := Load%v(data)\n", structName)
+		fmt.Fprintf(builder, "\t\tif err != nil {\n")
+		fmt.Fprintf(builder, "\t\t\treturn nil, err\n")
+		fmt.Fprintf(builder, "\t\t}\n")
+		fmt.Fprintf(builder, "\t\tdb.%v = valueSlice\n", structName)
+		fmt.Fprintf(builder, "\t}\n")
+	}
+	fmt.Fprintf(builder, "\tdb.BuildIndex()\n")
+	fmt.Fprintf(builder, "\treturn db, nil\n")
+	fmt.Fprintf(builder, "}\n\n")
+
+	fmt.Fprintf(builder, "// BuildIndex 在修改 DB 中的表之后重建索引\n")
+	fmt.Fprintf(builder, "func (db *DB) BuildIndex() {\n")
+	for _, target := range targetSlice {
+		fmt.Fprintf(builder, "\tdb.%v = make(map[string][]*%v)\n", indexFieldName(target), ToCamelCase(target.File))
+		fmt.Fprintf(builder, "\tfor _, v := range db.%v {\n", ToCamelCase(target.File))
+		fmt.Fprintf(builder, "\t\tkey := formationKey(v.%v)\n", ToCamelCase(target.Field))
+		fmt.Fprintf(builder, "\t\tdb.%v[key] = append(db.%v[key], v)\n", indexFieldName(target), indexFieldName(target))
+		fmt.Fprintf(builder, "\t}\n")
+	}
+	fmt.Fprintf(builder, "}\n\n")
+
+	for _, target := range targetSlice {
+		fmt.Fprintf(builder, "// %v 返回 %v 等于任意一个值的 %v\n", indexFunctionName(target), target.Field, target.File)
+		fmt.Fprintf(builder, "func (db *DB) %v(valueSlice ...string) []*%v {\n", indexFunctionName(target), ToCamelCase(target.File))
+		fmt.Fprintf(builder, "\tr := make([]*%v, 0, len(valueSlice))\n", ToCamelCase(target.File))
+		fmt.Fprintf(builder, "\tfor _, value := range valueSlice {\n")
+		fmt.Fprintf(builder, "\t\tr = append(r, db.%v[value]...)\n", indexFieldName(target))
+		fmt.Fprintf(builder, "\t}\n")
+		fmt.Fprintf(builder, "\treturn r\n")
+		fmt.Fprintf(builder, "}\n\n")
+	}
+}
+
+// generateReferenceAccessor 为每个引用生成 <字段><被引用表> 形式的方法，同一字段引用同一张表的多个字段时追加 By<被引用字段>
+func generateReferenceAccessor(builder *strings.Builder, referenceSlice []*reference) {
+	accessorCountMap := make(map[string]int)
+	for _, r := range referenceSlice {
+		accessorCountMap[r.File+"."+r.Field+"."+r.Target.File]++
+	}
+
+	for _, r := range referenceSlice {
+		structName := ToCamelCase(r.File)
+		targetStructName := ToCamelCase(r.Target.File)
+		accessorName := ToCamelCase(r.Field) + targetStructName
+		if accessorCountMap[r.File+"."+r.Field+"."+r.Target.File] > 1 {
+			accessorName += "By" + ToCamelCase(r.Target.Field)
+		}
+		cell := fmt.Sprintf("formationKey(r.%v)", ToCamelCase(r.Field))
+
+		fmt.Fprintf(builder, "// %v 按 %v 的 formation 解析引用的 %v.%v\n", accessorName, r.Field, r.Target.File, r.Target.Field)
+		fmt.Fprintf(builder, "func (r *%v) %v(db *DB) []*%v {\n", structName, accessorName, targetStructName)
+		if len(r.BranchField) == 0 {
+			fmt.Fprintf(builder, "\treturn db.%v(%v...)\n", indexFunctionName(r.Target), referenceSlotCall(cell, r.BranchSlotMap[""]))
+		} else {
+			fmt.Fprintf(builder, "\tvar valueSlice []string\n")
+			fmt.Fprintf(builder, "\tswitch formationKey(r.%v) {\n", ToCamelCase(r.BranchField))
+			for _, branchValue := range r.BranchValueSlice {
+				fmt.Fprintf(builder, "\tcase %q:\n", branchValue)
+				fmt.Fprintf(builder, "\t\tvalueSlice = %v\n", referenceSlotCall(cell, r.BranchSlotMap[branchValue]))
+			}
+			fmt.Fprintf(builder, "\t}\n")
+			fmt.Fprintf(builder, "\treturn db.%v(valueSlice...)\n", indexFunctionName(r.Target))
+		}
+		fmt.Fprintf(builder, "}\n\n")
+	}
+}
+
+func referenceSlotCall(cell string, slot *referenceSlot) string {
+	indexStringSlice := make([]string, 0, len(slot.IndexSlice))
+	for _, index := range slot.IndexSlice {
+		indexStringSlice = append(indexStringSlice, fmt.Sprintf("%v", index))
+	}
+	return fmt.Sprintf("formationReference(%v, %q, %q, %v, []int{%v})", cell, slot.GroupSeparator, slot.SlotSeparator, slot.SlotCount, strings.Join(indexStringSlice, ", "))
+}
+
+const formationReferenceFunctionCode = `// formationKey 返回字段值在单元格中的文本，浮点数不使用科学计数法，1000000 不会被格式化为 1e+06
+func formationKey(v interface{}) string {
+	switch value := v.(type) {
+	case float32:
+		return strconv.FormatFloat(float64(value), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// formationReference 按 formation 的分隔符拆分单元格，取出被引用的值
+func formationReference(cell, groupSeparator, slotSeparator string, slotCount int, indexSlice []int) []string {
+	groupSlice := []string{cell}
+	if len(groupSeparator) != 0 {
+		groupSlice = strings.Split(cell, groupSeparator)
+	}
+	r := make([]string, 0, len(groupSlice))
+	for _, group := range groupSlice {
+		if len(slotSeparator) == 0 {
+			r = append(r, group)
+			continue
+		}
+		slotSlice := strings.Split(group, slotSeparator)
+		if slotCount == 1 {
+			r = append(r, slotSlice...)
+		} else if len(slotSlice) == slotCount {
+			for _, index := range indexSlice {
+				r = append(r, slotSlice[index])
+			}
+		}
+	}
+	return r
+}
+`
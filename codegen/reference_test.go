@@ -0,0 +1,113 @@
+package codegen
+
+import (
+	"encoding/csv"
+	"go-formation/utility"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func testLoadTable(t *testing.T, name, csvContent string) *utility.Table {
+	loader, err := utility.NewTableLoader(name, csv.NewReader(strings.NewReader(csvContent)), utility.DefaultHeaderLayout, utility.EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+const (
+	testItemCsv   = "comment,comment\n,\nserver,server\nid,name\nint,string\n1001,a\n"
+	testRewardCsv = "comment,comment\n,\"format(Item.id,PH;Item.id,PH)\"\nserver,server\nid,items\nint,string\n1,\"1001,10\"\n"
+)
+
+func TestGenerateReference(t *testing.T) {
+	g := NewGenerator("config")
+	g.AddTable(testLoadTable(t, "Item", testItemCsv))
+	g.AddTable(testLoadTable(t, "Reward", testRewardCsv))
+	source, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expect := range []string{
+		"type Item struct",
+		"type Reward struct",
+		`dataMap["Reward"]`,
+		"func (db *DB) ItemByID(valueSlice ...string) []*Item",
+		"func (r *Reward) ItemsItem(db *DB) []*Item",
+	} {
+		if !strings.Contains(string(source), expect) {
+			t.Errorf("generated code does not contain %q", expect)
+		}
+	}
+}
+
+func TestGenerateTableWithoutName(t *testing.T) {
+	table := testLoadTable(t, "Item", testItemCsv)
+	table.Name = ""
+	g := NewGenerator("config")
+	g.AddTable(table)
+	if _, err := g.Generate(); err == nil {
+		t.Errorf("Generate table without name expect error")
+	}
+}
+
+const testGeneratedMainCode = `package main
+
+import "fmt"
+
+func main() {
+	db, err := LoadDB(map[string][]byte{
+		"Prize":  []byte(` + "`" + `{"Format":{"id":0},"Data":[[1000000],[0.5]]}` + "`" + `),
+		"Reward": []byte(` + "`" + `{"Format":{"id":0,"prize":1},"Data":[[1,"1000000;0.5"]]}` + "`" + `),
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(len(db.Reward[0].PrizePrize(db)))
+}
+`
+
+func TestGeneratedFloatKey(t *testing.T) {
+	if testing.Short() {
+		t.Skip("go run generated code")
+	}
+	goPath, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip(err)
+	}
+	g := NewGenerator("main")
+	g.AddTable(testLoadTable(t, "Prize", "comment\n\"\"\nserver\nid\ndouble\n1000000\n0.5\n"))
+	g.AddTable(testLoadTable(t, "Reward", "comment,comment\n,format(Prize.id;Prize.id)\nserver,server\nid,prize\nint,string\n1,1000000;0.5\n"))
+	source, err := g.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(source), "fmt.Sprint(v.") {
+		t.Errorf("generated index key uses fmt.Sprint")
+	}
+
+	dir := t.TempDir()
+	for name, content := range map[string][]byte{"config.go": source, "main.go": []byte(testGeneratedMainCode)} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), content, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cmd := exec.Command(goPath, "run", "config.go", "main.go")
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GO111MODULE=off")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("go run generated code: %v\n%s", err, output)
+	}
+	if string(output) != "2" {
+		t.Errorf("PrizePrize found %s rows, expect 2", output)
+	}
+}
@@ -0,0 +1,248 @@
+package codegen
+
+import (
+	"fmt"
+	"go-formation/utility"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// 字段名中按 golint 习惯全部大写的缩写
+var initialismSet = map[string]bool{
+	"ID":   true,
+	"URL":  true,
+	"UID":  true,
+	"UUID": true,
+	"HP":   true,
+	"MP":   true,
+	"NPC":  true,
+	"UI":   true,
+}
+
+// ToCamelCase 将 group_id 形式的表名或字段名转为 GroupID 形式的 Go 标识符
+func ToCamelCase(name string) string {
+	builder := strings.Builder{}
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(word); initialismSet[upper] {
+			builder.WriteString(upper)
+			continue
+		}
+		runeSlice := []rune(word)
+		runeSlice[0] = unicode.ToUpper(runeSlice[0])
+		builder.WriteString(string(runeSlice))
+	}
+	identifier := builder.String()
+	if len(identifier) == 0 {
+		return ""
+	}
+	if unicode.IsDigit([]rune(identifier)[0]) {
+		identifier = "F" + identifier
+	}
+	return identifier
+}
+
+// GoType 返回类型行中的类型对应的 Go 类型，未注册 GoType 的类型为 interface{}
+func GoType(Type string) string {
+	p := utility.GetTypeParser(Type)
+	if p == nil || len(p.GoType) == 0 {
+		return "interface{}"
+	}
+	if utility.GetEnumValueMap(Type) != nil {
+		return ToCamelCase(p.GoType)
+	}
+	return p.GoType
+}
+
+// Generator 为一组配置表生成 Go 代码，每张表生成一个结构体以及从 Format/Data json 加载的函数，
+// 所有表汇总到 DB 中，formation 引用了生成器中其他表的字段生成返回被引用行的方法
+type Generator struct {
+	Package    string
+	TableSlice []*utility.Table
+}
+
+func NewGenerator(pkg string) *Generator {
+	return &Generator{Package: pkg}
+}
+
+func (g *Generator) AddTable(table *utility.Table) {
+	g.TableSlice = append(g.TableSlice, table)
+}
+
+// Generate 生成经过 gofmt 的 Go 源码
+func (g *Generator) Generate() ([]byte, error) {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "// Code generated by go-formation. DO NOT EDIT.\n\n")
+	fmt.Fprintf(builder, "package %v\n\n", g.Package)
+
+	referenceSlice := make([]*reference, 0)
+	for _, table := range g.TableSlice {
+		// 结构体名、LoadDB 的键以及 formation 的引用都依赖表名
+		if len(table.Name) == 0 {
+			return nil, fmt.Errorf("table with keys %v has no name, load it by utility.NewTableLoader with the table name", table.Format)
+		}
+		tableReferenceSlice, err := g.collectTableReference(table)
+		if err != nil {
+			return nil, err
+		}
+		referenceSlice = append(referenceSlice, tableReferenceSlice...)
+	}
+
+	importSlice := []string{"encoding/json", "fmt"}
+	if len(referenceSlice) != 0 {
+		importSlice = append(importSlice, "strconv", "strings")
+	}
+	if g.usesGoType("time.") {
+		importSlice = append(importSlice, "time")
+	}
+	fmt.Fprintf(builder, "import (\n")
+	for _, importPath := range importSlice {
+		fmt.Fprintf(builder, "\t%q\n", importPath)
+	}
+	fmt.Fprintf(builder, ")\n\n")
+
+	g.generateEnum(builder)
+	for _, table := range g.TableSlice {
+		if err := generateTableStruct(builder, table); err != nil {
+			return nil, err
+		}
+		generateTableLoader(builder, table)
+	}
+	g.generateDB(builder, referenceSlice)
+	generateReferenceAccessor(builder, referenceSlice)
+	builder.WriteString(decodeFieldFunctionCode)
+	if len(referenceSlice) != 0 {
+		builder.WriteString("\n")
+		builder.WriteString(formationReferenceFunctionCode)
+	}
+
+	source, err := format.Source([]byte(builder.String()))
+	if err != nil {
+		return nil, fmt.Errorf("format generated code occurs error: %v", err)
+	}
+	return source, nil
+}
+
+func (g *Generator) usesGoType(prefix string) bool {
+	for _, table := range g.TableSlice {
+		for _, key := range table.KeySlice {
+			if strings.Contains(GoType(key.Type), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// generateEnum 为表中用到的枚举生成具名类型与常量
+func (g *Generator) generateEnum(builder *strings.Builder) {
+	enumTypeSet := make(map[string]bool)
+	enumTypeSlice := make([]string, 0)
+	for _, table := range g.TableSlice {
+		for _, key := range table.KeySlice {
+			if utility.GetEnumValueMap(key.Type) == nil || enumTypeSet[GoType(key.Type)] {
+				continue
+			}
+			enumTypeSet[GoType(key.Type)] = true
+			enumTypeSlice = append(enumTypeSlice, key.Type)
+		}
+	}
+	sort.Strings(enumTypeSlice)
+
+	for _, enumType := range enumTypeSlice {
+		goType := GoType(enumType)
+		enumValueMap := utility.GetEnumValueMap(enumType)
+		enumNameSlice := make([]string, 0, len(enumValueMap))
+		for enumName := range enumValueMap {
+			enumNameSlice = append(enumNameSlice, enumName)
+		}
+		sort.Slice(enumNameSlice, func(i, j int) bool {
+			if enumValueMap[enumNameSlice[i]] != enumValueMap[enumNameSlice[j]] {
+				return enumValueMap[enumNameSlice[i]] < enumValueMap[enumNameSlice[j]]
+			}
+			return enumNameSlice[i] < enumNameSlice[j]
+		})
+
+		fmt.Fprintf(builder, "type %v int64\n\n", goType)
+		fmt.Fprintf(builder, "const (\n")
+		for _, enumName := range enumNameSlice {
+			fmt.Fprintf(builder, "\t%v_%v %v = %v\n", goType, ToCamelCase(enumName), goType, enumValueMap[enumName])
+		}
+		fmt.Fprintf(builder, ")\n\n")
+	}
+}
+
+func generateTableStruct(builder *strings.Builder, table *utility.Table) error {
+	structName := ToCamelCase(table.Name)
+	if len(structName) == 0 {
+		return fmt.Errorf("table name '%v' can not convert to go identifier", table.Name)
+	}
+
+	fmt.Fprintf(builder, "// %v generated from table %v\n", structName, table.Name)
+	fmt.Fprintf(builder, "type %v struct {\n", structName)
+	fieldNameSet := make(map[string]string)
+	for _, key := range table.KeySlice {
+		fieldName := ToCamelCase(key.Name)
+		if len(fieldName) == 0 {
+			return fmt.Errorf("table %v key '%v' can not convert to go identifier", table.Name, key.Name)
+		}
+		if otherKey, hasFieldName := fieldNameSet[fieldName]; hasFieldName {
+			return fmt.Errorf("table %v key %v and %v convert to same go identifier %v", table.Name, key.Name, otherKey, fieldName)
+		}
+		fieldNameSet[fieldName] = key.Name
+
+		for _, comment := range []string{key.Comment, key.Description} {
+			if comment = strings.TrimSpace(comment); len(comment) != 0 {
+				fmt.Fprintf(builder, "\t// %v\n", strings.Join(strings.Fields(comment), " "))
+			}
+		}
+		fmt.Fprintf(builder, "\t%v %v `json:%q`\n", fieldName, GoType(key.Type), key.Name)
+	}
+	fmt.Fprintf(builder, "}\n\n")
+	return nil
+}
+
+func generateTableLoader(builder *strings.Builder, table *utility.Table) {
+	structName := ToCamelCase(table.Name)
+	fmt.Fprintf(builder, "// Load%v 从 utility.ProcessCsv 导出的 Format/Data json 加载 %v\n", structName, table.Name)
+	fmt.Fprintf(builder, "func Load%v(data []byte) ([]*%v, error) {\n", structName, structName)
+	fmt.Fprintf(builder, "\tjsonObject := &struct {\n")
+	fmt.Fprintf(builder, "\t\tFormat map[string]int `json:\"Format\"`\n")
+	fmt.Fprintf(builder, "\t\tData [][]json.RawMessage `json:\"Data\"`\n")
+	fmt.Fprintf(builder, "\t}{}\n")
+	fmt.Fprintf(builder, "\tif err := json.Unmarshal(data, jsonObject); err != nil {\n")
+	fmt.Fprintf(builder, "\t\treturn nil, err\n")
+	fmt.Fprintf(builder, "\t}\n")
+	fmt.Fprintf(builder, "\tr := make([]*%v, 0, len(jsonObject.Data))\n", structName)
+	if len(table.KeySlice) == 0 {
+		fmt.Fprintf(builder, "\tfor range jsonObject.Data {\n")
+	} else {
+		fmt.Fprintf(builder, "\tfor row, rowDataSlice := range jsonObject.Data {\n")
+	}
+	fmt.Fprintf(builder, "\t\tv := &%v{}\n", structName)
+	for _, key := range table.KeySlice {
+		fmt.Fprintf(builder, "\t\tif err := decodeField(jsonObject.Format, rowDataSlice, %q, &v.%v); err != nil {\n", key.Name, ToCamelCase(key.Name))
+		fmt.Fprintf(builder, "\t\t\treturn nil, fmt.Errorf(\"%v row %%v: %%v\", row, err)\n", table.Name)
+		fmt.Fprintf(builder, "\t\t}\n")
+	}
+	fmt.Fprintf(builder, "\t\tr = append(r, v)\n")
+	fmt.Fprintf(builder, "\t}\n")
+	fmt.Fprintf(builder, "\treturn r, nil\n")
+	fmt.Fprintf(builder, "}\n\n")
+}
+
+const decodeFieldFunctionCode = `// decodeField 按 Format 中的下标解码一列，json 中没有该列时保留零值
+func decodeField(format map[string]int, rowDataSlice []json.RawMessage, name string, v interface{}) error {
+	index, hasField := format[name]
+	if !hasField || index < 0 || index >= len(rowDataSlice) {
+		return nil
+	}
+	if err := json.Unmarshal(rowDataSlice[index], v); err != nil {
+		return fmt.Errorf("field %v: %v", name, err)
+	}
+	return nil
+}
+`
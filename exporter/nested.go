@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"fmt"
+	"go-formation/formation"
+	"go-formation/utility"
+	"io"
+	"sort"
+)
+
+// NESTED_TYPE 展开后的列在 Types 中的类型，值为嵌套的数组与对象而不是类型行中声明的类型
+const NESTED_TYPE = "nested"
+
+// NestedJsonOption 控制 formation 单元格展开为嵌套 json 的方式
+type NestedJsonOption struct {
+	// PlaceHolderNameMap 字段名到占位符名字的映射，依次替换元组中的 ph1、ph2...，名字比占位符少时返回错误
+	PlaceHolderNameMap map[string][]string
+}
+
+// ExpandTable 返回 formation 单元格按 formation 展开后的表，元组展开为对象，列表展开为数组，
+// 空单元格展开为 null，没有 formation 的列保持原值，展开的列在 Types 中为 NESTED_TYPE
+func ExpandTable(table *utility.Table, option *NestedJsonOption) (*utility.Table, error) {
+	if option == nil {
+		option = &NestedJsonOption{}
+	}
+	fieldSlice := make([]string, 0, len(table.FormationMap))
+	for field := range table.FormationMap {
+		fieldSlice = append(fieldSlice, field)
+	}
+	sort.Strings(fieldSlice)
+
+	formationSlice := make([]*formation.Formation, 0, len(fieldSlice))
+	for _, field := range fieldSlice {
+		if _, hasField := table.Format[field]; !hasField {
+			continue
+		}
+		f, err := formation.NewFormation(table.Name, field, table.FormationMap[field])
+		if err != nil {
+			return nil, err
+		}
+		if f != nil {
+			formationSlice = append(formationSlice, f)
+		}
+	}
+
+	expandTable := *table
+	expandTable.KeySlice = make([]*utility.KeyIndex, 0, len(table.KeySlice))
+	for _, key := range table.KeySlice {
+		expandKey := *key
+		for _, f := range formationSlice {
+			if f.Field == key.Name {
+				expandKey.Type = NESTED_TYPE
+				break
+			}
+		}
+		expandTable.KeySlice = append(expandTable.KeySlice, &expandKey)
+	}
+	expandTable.Data = make([][]interface{}, 0, len(table.Data))
+	for row, rowDataSlice := range table.Data {
+		expandRowDataSlice := make([]interface{}, len(rowDataSlice))
+		copy(expandRowDataSlice, rowDataSlice)
+		for _, f := range formationSlice {
+			v, err := f.ParseRowValue(table.Format, rowDataSlice)
+			if err != nil {
+				return nil, fmt.Errorf("row %v: %v", row, err)
+			}
+			if err := v.RenamePlaceHolder(option.PlaceHolderNameMap[f.Field]); err != nil {
+				return nil, fmt.Errorf("row %v: %v.%v: %v", row, f.File, f.Field, err)
+			}
+			expandRowDataSlice[table.Format[f.Field]] = v.Interface()
+		}
+		expandTable.Data = append(expandTable.Data, expandRowDataSlice)
+	}
+	return &expandTable, nil
+}
+
+// WriteNestedJSON 与 Table.WriteJSON 相同的 Format/Data 结构，formation 单元格展开为嵌套的数组与对象
+func WriteNestedJSON(w io.Writer, table *utility.Table, option *NestedJsonOption) error {
+	expandTable, err := ExpandTable(table, option)
+	if err != nil {
+		return err
+	}
+	return expandTable.WriteJSON(w)
+}
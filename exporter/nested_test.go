@@ -0,0 +1,116 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"go-formation/utility"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func testLoadTable(t *testing.T, name, content string) *utility.Table {
+	loader, err := utility.NewTableLoader(name, csv.NewReader(strings.NewReader(content)), utility.DefaultHeaderLayout, utility.EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func TestExpandTable(t *testing.T) {
+	for _, c := range []struct {
+		name          string
+		content       string
+		option        *NestedJsonOption
+		expect        [][]interface{}
+		expectType    string
+		errorContains string
+	}{
+		{
+			"list of tuples",
+			"comment,comment\n,\"format(Item.id,PH;Item.id,PH?)\"\nserver,server\nid,reward\nint,string\n1,\"1001,60;1002,40\"\n",
+			nil,
+			[][]interface{}{{float64(1), []interface{}{map[string]interface{}{"id": float64(1001), "ph1": float64(60)}, map[string]interface{}{"id": float64(1002), "ph1": float64(40)}}}},
+			NESTED_TYPE,
+			"",
+		},
+		{
+			"empty cell",
+			"comment,comment\n,\"format(Item.id,PH?)\"\nserver,server\nid,reward\nint,string\n1,\n",
+			nil,
+			[][]interface{}{{float64(1), nil}},
+			NESTED_TYPE,
+			"",
+		},
+		{
+			"placeholder names",
+			"comment,comment\n,\"format(Item.id,PH,PH)\"\nserver,server\nid,reward\nint,string\n1,\"1001,60,2\"\n",
+			&NestedJsonOption{PlaceHolderNameMap: map[string][]string{"reward": {"weight", "count"}}},
+			[][]interface{}{{float64(1), map[string]interface{}{"id": float64(1001), "weight": float64(60), "count": float64(2)}}},
+			NESTED_TYPE,
+			"",
+		},
+		{
+			"empty placeholder name keeps ph",
+			"comment,comment\n,\"format(Item.id,PH,PH)\"\nserver,server\nid,reward\nint,string\n1,\"1001,60,2\"\n",
+			&NestedJsonOption{PlaceHolderNameMap: map[string][]string{"reward": {"", "count"}}},
+			[][]interface{}{{float64(1), map[string]interface{}{"id": float64(1001), "ph1": float64(60), "count": float64(2)}}},
+			NESTED_TYPE,
+			"",
+		},
+		{
+			"fewer placeholder names",
+			"comment,comment\n,\"format(Item.id,PH,PH)\"\nserver,server\nid,reward\nint,string\n1,\"1001,60,2\"\n",
+			&NestedJsonOption{PlaceHolderNameMap: map[string][]string{"reward": {"weight"}}},
+			nil,
+			"",
+			"more than 1 placeholders",
+		},
+		{
+			"decoration",
+			"comment,comment,comment\n,,\"format(Reward.type(1):Item.id,PH|Reward.type(2):Hero.id)\"\nserver,server,server\nid,type,reward\nint,int,string\n1,1,\"1001,5\"\n2,2,3001\n",
+			&NestedJsonOption{PlaceHolderNameMap: map[string][]string{"reward": {"count"}}},
+			[][]interface{}{
+				{float64(1), float64(1), map[string]interface{}{"id": float64(1001), "count": float64(5)}},
+				{float64(2), float64(2), float64(3001)},
+			},
+			NESTED_TYPE,
+			"",
+		},
+	} {
+		table := testLoadTable(t, "Reward", c.content)
+		buffer := &bytes.Buffer{}
+		err := WriteNestedJSON(buffer, table, c.option)
+		if len(c.errorContains) != 0 {
+			if err == nil || !strings.Contains(err.Error(), c.errorContains) {
+				t.Errorf("%v: WriteNestedJSON error = %v, expect contain %v", c.name, err, c.errorContains)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: WriteNestedJSON error: %v", c.name, err)
+			continue
+		}
+		o := &struct {
+			Data  [][]interface{}   `json:"Data"`
+			Types map[string]string `json:"Types"`
+		}{}
+		if err := json.Unmarshal(buffer.Bytes(), o); err != nil {
+			t.Fatalf("%v: json %v: %v", c.name, buffer.String(), err)
+		}
+		if !reflect.DeepEqual(o.Data, c.expect) {
+			t.Errorf("%v: Data = %v, expect %v", c.name, o.Data, c.expect)
+		}
+		if o.Types["reward"] != c.expectType || o.Types["id"] != "int" {
+			t.Errorf("%v: Types = %v, expect reward %v", c.name, o.Types, c.expectType)
+		}
+		if table.Types()["reward"] != "string" {
+			t.Errorf("%v: ExpandTable changed the source table Types %v", c.name, table.Types())
+		}
+	}
+}
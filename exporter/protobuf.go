@@ -0,0 +1,289 @@
+package exporter
+
+import (
+	"fmt"
+	"go-formation/codegen"
+	"go-formation/utility"
+	"io"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type protoKind int
+
+const (
+	PROTO_INT protoKind = iota + 1
+	PROTO_UINT
+	PROTO_BOOL
+	PROTO_FLOAT
+	PROTO_DOUBLE
+	PROTO_STRING
+	PROTO_REPEATED_INT
+	PROTO_REPEATED_STRING
+	PROTO_MAP_INT_INT
+	PROTO_DATETIME
+	PROTO_DURATION
+)
+
+// 类型行中的类型到 proto 类型，枚举导出为 int64，未知类型按字符串导出
+var protoKindMap = map[string]protoKind{
+	"int":          PROTO_INT,
+	"int64":        PROTO_INT,
+	"int32":        PROTO_INT,
+	"int16":        PROTO_INT,
+	"int8":         PROTO_INT,
+	"uint":         PROTO_UINT,
+	"uint64":       PROTO_UINT,
+	"uint32":       PROTO_UINT,
+	"uint16":       PROTO_UINT,
+	"uint8":        PROTO_UINT,
+	"bool":         PROTO_BOOL,
+	"float":        PROTO_FLOAT,
+	"float32":      PROTO_FLOAT,
+	"double":       PROTO_DOUBLE,
+	"float64":      PROTO_DOUBLE,
+	"string":       PROTO_STRING,
+	"[]int":        PROTO_REPEATED_INT,
+	"[]int64":      PROTO_REPEATED_INT,
+	"[]int32":      PROTO_REPEATED_INT,
+	"[]string":     PROTO_REPEATED_STRING,
+	"map<int,int>": PROTO_MAP_INT_INT,
+	"datetime":     PROTO_DATETIME,
+	"duration":     PROTO_DURATION,
+}
+
+// protoField 表中一列对应的 proto 字段，字段编号按列顺序从 1 开始
+type protoField struct {
+	Name   string
+	Number int
+	Kind   protoKind
+	Key    *utility.KeyIndex
+}
+
+func getProtoKind(Type string) protoKind {
+	if kind, hasKind := protoKindMap[strings.Join(strings.Fields(Type), "")]; hasKind {
+		return kind
+	}
+	if utility.GetEnumValueMap(Type) != nil {
+		return PROTO_INT
+	}
+	return PROTO_STRING
+}
+
+func (f *protoField) schemaType() string {
+	switch f.Kind {
+	case PROTO_INT, PROTO_DATETIME, PROTO_DURATION:
+		if f.Key.Type == "int32" || f.Key.Type == "int16" || f.Key.Type == "int8" {
+			return "int32"
+		}
+		return "int64"
+	case PROTO_UINT:
+		if f.Key.Type == "uint32" || f.Key.Type == "uint16" || f.Key.Type == "uint8" {
+			return "uint32"
+		}
+		return "uint64"
+	case PROTO_BOOL:
+		return "bool"
+	case PROTO_FLOAT:
+		return "float"
+	case PROTO_DOUBLE:
+		return "double"
+	case PROTO_REPEATED_INT:
+		return "repeated int64"
+	case PROTO_REPEATED_STRING:
+		return "repeated string"
+	case PROTO_MAP_INT_INT:
+		return "map<int64, int64>"
+	default:
+	}
+	return "string"
+}
+
+// ToProtoName 将列名转为合法的 proto 字段名
+func ToProtoName(name string) string {
+	builder := strings.Builder{}
+	for _, r := range name {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune('_')
+		}
+	}
+	protoName := builder.String()
+	if len(protoName) == 0 || unicode.IsDigit(rune(protoName[0])) {
+		protoName = "f_" + protoName
+	}
+	return protoName
+}
+
+func getProtoFieldSlice(table *utility.Table) ([]*protoField, error) {
+	protoFieldSlice := make([]*protoField, 0, len(table.KeySlice))
+	nameSet := make(map[string]string)
+	for index, key := range table.KeySlice {
+		name := ToProtoName(key.Name)
+		if otherKey, hasName := nameSet[name]; hasName {
+			return nil, fmt.Errorf("table %v key %v and %v convert to same proto field %v", table.Name, key.Name, otherKey, name)
+		}
+		nameSet[name] = key.Name
+		protoFieldSlice = append(protoFieldSlice, &protoField{
+			Name:   name,
+			Number: index + 1,
+			Kind:   getProtoKind(key.Type),
+			Key:    key,
+		})
+	}
+	return protoFieldSlice, nil
+}
+
+// GenerateProtoSchema 为每张表生成一行的消息 <Table> 与整张表的消息 <Table>Table { repeated <Table> rows = 1; }，
+// 字段编号按列顺序分配，调整列顺序会改变编号
+func GenerateProtoSchema(pkg string, tableSlice []*utility.Table) (string, error) {
+	builder := &strings.Builder{}
+	fmt.Fprintf(builder, "// Code generated by go-formation. DO NOT EDIT.\n\n")
+	fmt.Fprintf(builder, "syntax = \"proto3\";\n\n")
+	fmt.Fprintf(builder, "package %v;\n", pkg)
+
+	for _, table := range tableSlice {
+		messageName := codegen.ToCamelCase(table.Name)
+		if len(messageName) == 0 {
+			return "", fmt.Errorf("table name '%v' can not convert to proto message name", table.Name)
+		}
+		protoFieldSlice, err := getProtoFieldSlice(table)
+		if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(builder, "\nmessage %v {\n", messageName)
+		for _, f := range protoFieldSlice {
+			comment := strings.Join(strings.Fields(f.Key.Comment), " ")
+			switch f.Kind {
+			case PROTO_DATETIME:
+				comment = strings.TrimSpace(comment + " unix seconds")
+			case PROTO_DURATION:
+				comment = strings.TrimSpace(comment + " milliseconds")
+			case PROTO_INT:
+				if utility.GetEnumValueMap(f.Key.Type) != nil {
+					comment = strings.TrimSpace(comment + " enum " + f.Key.Type)
+				}
+			default:
+			}
+			if len(comment) != 0 {
+				fmt.Fprintf(builder, "  // %v\n", comment)
+			}
+			fmt.Fprintf(builder, "  %v %v = %v;\n", f.schemaType(), f.Name, f.Number)
+		}
+		fmt.Fprintf(builder, "}\n")
+		fmt.Fprintf(builder, "\nmessage %vTable {\n", messageName)
+		fmt.Fprintf(builder, "  repeated %v rows = 1;\n", messageName)
+		fmt.Fprintf(builder, "}\n")
+	}
+	return builder.String(), nil
+}
+
+// EncodeProtoTable 将整张表编码为 <Table>Table 消息
+func EncodeProtoTable(table *utility.Table) ([]byte, error) {
+	protoFieldSlice, err := getProtoFieldSlice(table)
+	if err != nil {
+		return nil, err
+	}
+	tableBuffer := &protoBuffer{}
+	for row, rowDataSlice := range table.Data {
+		rowBuffer := &protoBuffer{}
+		for _, f := range protoFieldSlice {
+			// 空单元格导出为 nil，与零值一样不写入消息
+			if f.Key.Index >= len(rowDataSlice) || rowDataSlice[f.Key.Index] == nil {
+				continue
+			}
+			if err := encodeProtoField(rowBuffer, f, rowDataSlice[f.Key.Index]); err != nil {
+				return nil, fmt.Errorf("table %v row %v field %v: %v", table.Name, row, f.Key.Name, err)
+			}
+		}
+		tableBuffer.EncodeMessage(1, rowBuffer.Bytes())
+	}
+	return tableBuffer.Bytes(), nil
+}
+
+// WriteProtoTable 将编码后的 <Table>Table 消息写入 w，每张表一个文件
+func WriteProtoTable(w io.Writer, table *utility.Table) error {
+	data, err := EncodeProtoTable(table)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func encodeProtoField(b *protoBuffer, f *protoField, value interface{}) error {
+	switch f.Kind {
+	case PROTO_INT:
+		v, ok := value.(int64)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not int64", value, value)
+		}
+		b.EncodeInt(f.Number, v)
+	case PROTO_UINT:
+		v, ok := value.(uint64)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not uint64", value, value)
+		}
+		b.EncodeUint(f.Number, v)
+	case PROTO_BOOL:
+		v, ok := value.(bool)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not bool", value, value)
+		}
+		b.EncodeBool(f.Number, v)
+	case PROTO_FLOAT, PROTO_DOUBLE:
+		v, ok := value.(float64)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not float64", value, value)
+		}
+		if f.Kind == PROTO_FLOAT {
+			b.EncodeFloat(f.Number, float32(v))
+		} else {
+			b.EncodeDouble(f.Number, v)
+		}
+	case PROTO_REPEATED_INT:
+		v, ok := value.([]int64)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not []int64", value, value)
+		}
+		b.EncodePackedInt(f.Number, v)
+	case PROTO_REPEATED_STRING:
+		v, ok := value.([]string)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not []string", value, value)
+		}
+		b.EncodeRepeatedString(f.Number, v)
+	case PROTO_MAP_INT_INT:
+		v, ok := value.(map[int64]int64)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not map[int64]int64", value, value)
+		}
+		keySlice := make([]int64, 0, len(v))
+		for k := range v {
+			keySlice = append(keySlice, k)
+		}
+		sort.Slice(keySlice, func(i, j int) bool { return keySlice[i] < keySlice[j] })
+		b.EncodeIntIntMap(f.Number, keySlice, v)
+	case PROTO_DATETIME:
+		v, ok := value.(time.Time)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not time.Time", value, value)
+		}
+		if !v.IsZero() {
+			b.EncodeInt(f.Number, v.Unix())
+		}
+	case PROTO_DURATION:
+		v, ok := value.(time.Duration)
+		if !ok {
+			return fmt.Errorf("value %v type %T is not time.Duration", value, value)
+		}
+		b.EncodeInt(f.Number, int64(v/time.Millisecond))
+	default:
+		b.EncodeString(f.Number, fmt.Sprintf("%v", value))
+	}
+	return nil
+}
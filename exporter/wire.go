@@ -0,0 +1,137 @@
+package exporter
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// protobuf wire type
+const (
+	WIRE_VARINT           = 0
+	WIRE_FIXED64          = 1
+	WIRE_LENGTH_DELIMITED = 2
+	WIRE_FIXED32          = 5
+)
+
+// protoBuffer 手写的 protobuf wire 编码器，只实现导出配置表需要的部分
+type protoBuffer struct {
+	buf []byte
+}
+
+func (b *protoBuffer) Bytes() []byte {
+	return b.buf
+}
+
+func (b *protoBuffer) appendVarint(v uint64) {
+	for v >= 0x80 {
+		b.buf = append(b.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	b.buf = append(b.buf, byte(v))
+}
+
+func (b *protoBuffer) appendTag(fieldNumber int, wireType int) {
+	b.appendVarint(uint64(fieldNumber)<<3 | uint64(wireType))
+}
+
+func (b *protoBuffer) appendFixed32(v uint32) {
+	b.buf = append(b.buf, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint32(b.buf[len(b.buf)-4:], v)
+}
+
+func (b *protoBuffer) appendFixed64(v uint64) {
+	b.buf = append(b.buf, 0, 0, 0, 0, 0, 0, 0, 0)
+	binary.LittleEndian.PutUint64(b.buf[len(b.buf)-8:], v)
+}
+
+func (b *protoBuffer) appendBytes(v []byte) {
+	b.appendVarint(uint64(len(v)))
+	b.buf = append(b.buf, v...)
+}
+
+// 以下方法按 proto3 的规则跳过零值
+
+// EncodeInt int32/int64 的负数按补码编码为 10 字节的 varint
+func (b *protoBuffer) EncodeInt(fieldNumber int, v int64) {
+	if v == 0 {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_VARINT)
+	b.appendVarint(uint64(v))
+}
+
+func (b *protoBuffer) EncodeUint(fieldNumber int, v uint64) {
+	if v == 0 {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_VARINT)
+	b.appendVarint(v)
+}
+
+func (b *protoBuffer) EncodeBool(fieldNumber int, v bool) {
+	if !v {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_VARINT)
+	b.appendVarint(1)
+}
+
+func (b *protoBuffer) EncodeFloat(fieldNumber int, v float32) {
+	if v == 0 {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_FIXED32)
+	b.appendFixed32(math.Float32bits(v))
+}
+
+func (b *protoBuffer) EncodeDouble(fieldNumber int, v float64) {
+	if v == 0 {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_FIXED64)
+	b.appendFixed64(math.Float64bits(v))
+}
+
+func (b *protoBuffer) EncodeString(fieldNumber int, v string) {
+	if len(v) == 0 {
+		return
+	}
+	b.appendTag(fieldNumber, WIRE_LENGTH_DELIMITED)
+	b.appendBytes([]byte(v))
+}
+
+// EncodeMessage 嵌套消息即使为空也需要写出，repeated 消息依赖它占位
+func (b *protoBuffer) EncodeMessage(fieldNumber int, v []byte) {
+	b.appendTag(fieldNumber, WIRE_LENGTH_DELIMITED)
+	b.appendBytes(v)
+}
+
+// EncodePackedInt repeated 标量在 proto3 中默认使用 packed 编码
+func (b *protoBuffer) EncodePackedInt(fieldNumber int, valueSlice []int64) {
+	if len(valueSlice) == 0 {
+		return
+	}
+	packed := &protoBuffer{}
+	for _, v := range valueSlice {
+		packed.appendVarint(uint64(v))
+	}
+	b.appendTag(fieldNumber, WIRE_LENGTH_DELIMITED)
+	b.appendBytes(packed.Bytes())
+}
+
+func (b *protoBuffer) EncodeRepeatedString(fieldNumber int, valueSlice []string) {
+	for _, v := range valueSlice {
+		b.appendTag(fieldNumber, WIRE_LENGTH_DELIMITED)
+		b.appendBytes([]byte(v))
+	}
+}
+
+// EncodeIntIntMap map<int64, int64> 的每个键值对编码为 key = 1, value = 2 的嵌套消息
+func (b *protoBuffer) EncodeIntIntMap(fieldNumber int, keySlice []int64, valueMap map[int64]int64) {
+	for _, k := range keySlice {
+		entry := &protoBuffer{}
+		entry.EncodeInt(1, k)
+		entry.EncodeInt(2, valueMap[k])
+		b.EncodeMessage(fieldNumber, entry.Bytes())
+	}
+}
@@ -0,0 +1,78 @@
+package exporter
+
+import (
+	"bytes"
+	"encoding/csv"
+	"go-formation/utility"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestProtoBufferEncode(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		encode func(b *protoBuffer)
+		expect []byte
+	}{
+		{"int", func(b *protoBuffer) { b.EncodeInt(1, 150) }, []byte{0x08, 0x96, 0x01}},
+		{"negative int", func(b *protoBuffer) { b.EncodeInt(1, -1) }, []byte{0x08, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"zero int", func(b *protoBuffer) { b.EncodeInt(1, 0) }, nil},
+		{"uint", func(b *protoBuffer) { b.EncodeUint(2, math.MaxUint64) }, []byte{0x10, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}},
+		{"bool", func(b *protoBuffer) { b.EncodeBool(3, true) }, []byte{0x18, 0x01}},
+		{"false bool", func(b *protoBuffer) { b.EncodeBool(3, false) }, nil},
+		{"float", func(b *protoBuffer) { b.EncodeFloat(1, 1) }, []byte{0x0d, 0x00, 0x00, 0x80, 0x3f}},
+		{"double", func(b *protoBuffer) { b.EncodeDouble(1, 1) }, []byte{0x09, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xf0, 0x3f}},
+		{"string", func(b *protoBuffer) { b.EncodeString(2, "testing") }, []byte{0x12, 0x07, 't', 'e', 's', 't', 'i', 'n', 'g'}},
+		{"empty string", func(b *protoBuffer) { b.EncodeString(2, "") }, nil},
+		{"empty message", func(b *protoBuffer) { b.EncodeMessage(1, nil) }, []byte{0x0a, 0x00}},
+		{"large field number", func(b *protoBuffer) { b.EncodeInt(16, 1) }, []byte{0x80, 0x01, 0x01}},
+		{"packed int", func(b *protoBuffer) { b.EncodePackedInt(4, []int64{3, 270, 86942}) }, []byte{0x22, 0x06, 0x03, 0x8e, 0x02, 0x9e, 0xa7, 0x05}},
+		{"repeated string", func(b *protoBuffer) { b.EncodeRepeatedString(1, []string{"a", ""}) }, []byte{0x0a, 0x01, 'a', 0x0a, 0x00}},
+		{"int int map", func(b *protoBuffer) { b.EncodeIntIntMap(5, []int64{1, 2}, map[int64]int64{1: 10, 2: 0}) }, []byte{0x2a, 0x04, 0x08, 0x01, 0x10, 0x0a, 0x2a, 0x02, 0x08, 0x02}},
+	} {
+		b := &protoBuffer{}
+		c.encode(b)
+		if !bytes.Equal(b.Bytes(), c.expect) {
+			t.Errorf("%v: encode = % x, expect % x", c.name, b.Bytes(), c.expect)
+		}
+	}
+}
+
+func TestEncodeProtoTable(t *testing.T) {
+	csvContent := "comment,comment,comment\n,,\nserver,server,server\nid,rate,name\nint,float,string\n1,0.1,a\n2,0,\n,,c\n"
+	loader, err := utility.NewTableLoader("Item", csv.NewReader(strings.NewReader(csvContent)), utility.DefaultHeaderLayout, utility.EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := EncodeProtoTable(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	firstRow := &protoBuffer{}
+	firstRow.EncodeInt(1, 1)
+	firstRow.EncodeFloat(2, 0.1)
+	firstRow.EncodeString(3, "a")
+	secondRow := &protoBuffer{}
+	secondRow.EncodeInt(1, 2)
+	// 空的 int 与 float 单元格导出为 nil，不写入消息
+	thirdRow := &protoBuffer{}
+	thirdRow.EncodeString(3, "c")
+	expect := &protoBuffer{}
+	expect.EncodeMessage(1, firstRow.Bytes())
+	expect.EncodeMessage(1, secondRow.Bytes())
+	expect.EncodeMessage(1, thirdRow.Bytes())
+	if !bytes.Equal(data, expect.Bytes()) {
+		t.Errorf("EncodeProtoTable = % x, expect % x", data, expect.Bytes())
+	}
+
+	table.Data[0][1] = "0.1"
+	if _, err := EncodeProtoTable(table); err == nil {
+		t.Errorf("EncodeProtoTable with string float value expect error")
+	}
+}
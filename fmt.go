@@ -0,0 +1,134 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-formation/formation"
+	"go-formation/utility"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+// runFmt 将配置表 formation 行中的 format(...) 改写为规范形式，没有指定文件时格式化标准输入中的单元格
+func runFmt(argSlice []string) int {
+	flagSet := flag.NewFlagSet("fmt", flag.ExitOnError)
+	check := flagSet.Bool("check", false, "list files whose formations are not canonical and exit with 1")
+	write := flagSet.Bool("w", false, "write result to the source file instead of stdout (keeping its encoding and BOM)")
+	projectPath := flagSet.String("project", "", "project config file providing the header layout")
+	flagSet.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: go-formation fmt [-check] [-w] [-project project.json] [file.csv ...]\n")
+		flagSet.PrintDefaults()
+	}
+	flagSet.Parse(argSlice)
+	if err := applyProjectFile(*projectPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v: %v\n", *projectPath, err)
+		return 2
+	}
+
+	if flagSet.NArg() == 0 {
+		content, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: read stdin occurs error: %v\n", err)
+			return 2
+		}
+		formatted, err := formation.FormatCell("", "", string(content))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 2
+		}
+		if *check {
+			if formatted != string(content) {
+				fmt.Println("<stdin>")
+				return 1
+			}
+			return 0
+		}
+		fmt.Print(formatted)
+		return 0
+	}
+
+	exitCode := 0
+	for _, path := range flagSet.Args() {
+		formatted, changed, err := formatCsvFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v: %v\n", path, err)
+			exitCode = 2
+			continue
+		}
+		if *check {
+			if changed {
+				fmt.Println(path)
+				if exitCode == 0 {
+					exitCode = 1
+				}
+			}
+			continue
+		}
+		if *write {
+			if !changed {
+				continue
+			}
+			if err := ioutil.WriteFile(path, formatted, 0644); err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v: %v\n", path, err)
+				exitCode = 2
+			}
+			continue
+		}
+		os.Stdout.Write(formatted)
+	}
+	return exitCode
+}
+
+// formatCsvFile 按项目配置的表头布局格式化 csv 文件的 formation 行，返回按原文件编码（包括 BOM）输出的文件内容以及是否有单元格被改写，
+// 只替换被改写的单元格，其他内容包括换行符与引号保持原样
+func formatCsvFile(path string) ([]byte, bool, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	e := utility.DetectEncoding(content, false)
+	decoded, err := e.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, false, err
+	}
+	text := string(decoded)
+	recordSlice, err := parseCsvRecordSlice(strings.NewReader(text))
+	if err != nil {
+		return nil, false, err
+	}
+
+	formationRow := headerRowIndex(utility.HEADER_FORMATION)
+	if formationRow < 0 || formationRow >= len(recordSlice) {
+		return content, false, nil
+	}
+	rangeSlice := csvRecordFieldRange(text, formationRow)
+	if len(rangeSlice) != len(recordSlice[formationRow]) {
+		return nil, false, fmt.Errorf("formation row %v has %v cells but %v fields are located", formationRow+1, len(recordSlice[formationRow]), len(rangeSlice))
+	}
+
+	changed := false
+	tableName := tableNameOf(path)
+	// 从后往前替换，前面字段的范围不受影响
+	for i := len(rangeSlice) - 1; i >= 0; i-- {
+		cell := recordSlice[formationRow][i]
+		formatted, err := formation.FormatCell(tableName, fieldNameOf(recordSlice, i), cell)
+		if err != nil {
+			return nil, false, err
+		}
+		if formatted == cell {
+			continue
+		}
+		start, end := rangeSlice[i][0], rangeSlice[i][1]
+		text = text[:start] + quoteCsvField(text[start:end], formatted) + text[end:]
+		changed = true
+	}
+	if !changed {
+		return content, false, nil
+	}
+	formattedContent, err := e.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		return nil, false, err
+	}
+	return formattedContent, true, nil
+}
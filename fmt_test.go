@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"go-formation/utility"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func TestFormatCsvFileEncoding(t *testing.T) {
+	content := "注释,注释\n,\"format( A.b , PH ; A.b,PH )\"\nserver,server\nid,reward\nint,string\n1,\"1001,1\"\n"
+	expect := "注释,注释\n,\"format(A.b,PH;A.b,PH)\"\nserver,server\nid,reward\nint,string\n1,\"1001,1\"\n"
+	for _, c := range []struct {
+		name string
+		e    encoding.Encoding
+	}{
+		{"utf-8", unicode.UTF8},
+		{"utf-8 bom", unicode.UTF8BOM},
+		{"utf-16le bom", unicode.UTF16(unicode.LittleEndian, unicode.UseBOM)},
+		{"gbk", simplifiedchinese.GBK},
+	} {
+		encoded, err := c.e.NewEncoder().Bytes([]byte(content))
+		if err != nil {
+			t.Fatal(err)
+		}
+		path := filepath.Join(t.TempDir(), "Reward.csv")
+		if err := ioutil.WriteFile(path, encoded, 0644); err != nil {
+			t.Fatal(err)
+		}
+		formatted, changed, err := formatCsvFile(path)
+		if err != nil {
+			t.Errorf("%v: formatCsvFile error: %v", c.name, err)
+			continue
+		}
+		expectEncoded, _ := c.e.NewEncoder().Bytes([]byte(expect))
+		if !changed || !bytes.Equal(formatted, expectEncoded) {
+			t.Errorf("%v: formatCsvFile = %q, changed %v, expect %q", c.name, formatted, changed, expectEncoded)
+		}
+	}
+}
+
+func TestHeaderRowIndexProjectLayout(t *testing.T) {
+	defer utility.SetDefaultHeaderLayout(utility.DefaultHeaderLayout)
+	path := filepath.Join(t.TempDir(), "project.json")
+	if err := ioutil.WriteFile(path, []byte(`{"Header":["key","type","formation"]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := applyProjectFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if headerRowIndex(utility.HEADER_KEY) != 0 || headerRowIndex(utility.HEADER_FORMATION) != 2 || headerRowIndex(utility.HEADER_OPS) != -1 {
+		t.Errorf("headerRowIndex does not follow project header layout %v", utility.GetDefaultHeaderLayout().RoleSlice)
+	}
+
+	csvPath := filepath.Join(t.TempDir(), "Reward.csv")
+	if err := ioutil.WriteFile(csvPath, []byte("id,reward\nint,string\n,format( A.b )\n1,1001\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	formatted, changed, err := formatCsvFile(csvPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := "id,reward\nint,string\n,format(A.b)\n1,1001\n"; !changed || string(formatted) != expect {
+		t.Errorf("formatCsvFile with project layout = %q, expect %q", formatted, expect)
+	}
+}
+
+func TestFormatCsvFileKeepLayout(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		content string
+		expect  string
+		changed bool
+	}{
+		{
+			"crlf",
+			"注释,注释\r\n,format( A.b )\r\nserver,server\r\nid,reward\r\nint,string\r\n1,\"1001\"\r\n\"2\",1002\r\n",
+			"注释,注释\r\n,format(A.b)\r\nserver,server\r\nid,reward\r\nint,string\r\n1,\"1001\"\r\n\"2\",1002\r\n",
+			true,
+		},
+		{
+			"quoted formation cell",
+			"注释,注释\r\n\"\",\"format( A.b , PH )\"\r\nserver,server\r\nid,reward\r\nint,string\r\n1,\"1001,1\"\r\n",
+			"注释,注释\r\n\"\",\"format(A.b,PH)\"\r\nserver,server\r\nid,reward\r\nint,string\r\n1,\"1001,1\"\r\n",
+			true,
+		},
+		{
+			"escaped quote in data cell",
+			"注释,注释\n,format( A.b )\nserver,server\nid,reward\nint,string\n1,\"a \"\"b\"\"\"\n",
+			"注释,注释\n,format(A.b)\nserver,server\nid,reward\nint,string\n1,\"a \"\"b\"\"\"\n",
+			true,
+		},
+		{
+			"empty lines and no trailing newline",
+			"\r\n注释,注释\r\n\r\n,format( A.b )\r\nserver,server\r\nid,reward\r\nint,string\r\n1,1001",
+			"\r\n注释,注释\r\n\r\n,format(A.b)\r\nserver,server\r\nid,reward\r\nint,string\r\n1,1001",
+			true,
+		},
+		{
+			"unchanged",
+			"注释,注释\r\n,\"format(A.b)\"\r\nserver,server\r\nid,reward\r\nint,string\r\n1, 1001\r\n",
+			"注释,注释\r\n,\"format(A.b)\"\r\nserver,server\r\nid,reward\r\nint,string\r\n1, 1001\r\n",
+			false,
+		},
+	} {
+		path := filepath.Join(t.TempDir(), "Reward.csv")
+		if err := ioutil.WriteFile(path, []byte(c.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		formatted, changed, err := formatCsvFile(path)
+		if err != nil {
+			t.Errorf("%v: formatCsvFile error: %v", c.name, err)
+			continue
+		}
+		if changed != c.changed || string(formatted) != c.expect {
+			t.Errorf("%v: formatCsvFile = %q, changed %v, expect %q", c.name, formatted, changed, c.expect)
+		}
+	}
+}
+
+func TestCsvRecordFieldRange(t *testing.T) {
+	text := "a,\"b,\"\"c\"\"\"\r\n\r\n,\"x\ny\",z\n"
+	for _, c := range []struct {
+		row    int
+		expect []string
+	}{
+		{0, []string{"a", "\"b,\"\"c\"\"\""}},
+		{1, []string{"", "\"x\ny\"", "z"}},
+		{2, nil},
+	} {
+		rangeSlice := csvRecordFieldRange(text, c.row)
+		fieldSlice := make([]string, 0)
+		for _, r := range rangeSlice {
+			fieldSlice = append(fieldSlice, text[r[0]:r[1]])
+		}
+		if c.expect == nil && rangeSlice != nil || c.expect != nil && strings.Join(fieldSlice, "|") != strings.Join(c.expect, "|") {
+			t.Errorf("csvRecordFieldRange(%v) = %q, expect %q", c.row, fieldSlice, c.expect)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package formation
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 槽位上的聚合注解，对单元格中同一槽位的所有值一起检查
+const (
+	// ANNOTATION_SUM PH:sum(10000) 所有值之和等于参数
+	ANNOTATION_SUM = "sum"
+	// ANNOTATION_UNIQUE A.b:unique 值不重复
+	ANNOTATION_UNIQUE = "unique"
+	// ANNOTATION_INCREASING PH:increasing 值严格递增
+	ANNOTATION_INCREASING = "increasing"
+	// ANNOTATION_COUNT A.b:count(1..5) 值的个数在范围内，范围的两端可以省略，count(3) 表示恰好 3 个
+	ANNOTATION_COUNT = "count"
+)
+
+// aggregator 聚合注解的实现，CheckArg 在检查数据前校验参数
+type aggregator struct {
+	CheckArg func(arg string) error
+	Check    func(arg string, valueSlice []*Value) error
+}
+
+var aggregatorMap map[string]*aggregator
+
+func init() {
+	aggregatorMap = map[string]*aggregator{
+		ANNOTATION_SUM: {
+			CheckArg: func(arg string) error {
+				_, err := strconv.ParseFloat(arg, 64)
+				return err
+			},
+			Check: func(arg string, valueSlice []*Value) error {
+				expect, _ := strconv.ParseFloat(arg, 64)
+				sum := 0.0
+				for _, v := range valueSlice {
+					f, err := valueToFloat(v)
+					if err != nil {
+						return err
+					}
+					sum += f
+				}
+				if math.Abs(sum-expect) > 1e-9*math.Max(1, math.Abs(expect)) {
+					return fmt.Errorf("sum %v is not equal %v", strconv.FormatFloat(sum, 'f', -1, 64), arg)
+				}
+				return nil
+			},
+		},
+		ANNOTATION_UNIQUE: {
+			CheckArg: noAggregatorArg,
+			Check: func(arg string, valueSlice []*Value) error {
+				valueIndexMap := make(map[string]int)
+				for index, v := range valueSlice {
+					c := fmt.Sprintf("%v", v.Scalar)
+					if lastIndex, hasValue := valueIndexMap[c]; hasValue {
+						return fmt.Errorf("value %v at %v and %v is duplicated", c, lastIndex, index)
+					}
+					valueIndexMap[c] = index
+				}
+				return nil
+			},
+		},
+		ANNOTATION_INCREASING: {
+			CheckArg: noAggregatorArg,
+			Check: func(arg string, valueSlice []*Value) error {
+				for index := 1; index < len(valueSlice); index++ {
+					last, err := valueToFloat(valueSlice[index-1])
+					if err != nil {
+						return err
+					}
+					current, err := valueToFloat(valueSlice[index])
+					if err != nil {
+						return err
+					}
+					if current <= last {
+						return fmt.Errorf("value %v at %v is not greater than %v", valueSlice[index].Scalar, index, valueSlice[index-1].Scalar)
+					}
+				}
+				return nil
+			},
+		},
+		ANNOTATION_COUNT: {
+			CheckArg: func(arg string) error {
+				_, _, err := parseCountRange(arg)
+				return err
+			},
+			Check: func(arg string, valueSlice []*Value) error {
+				min, max, _ := parseCountRange(arg)
+				if len(valueSlice) < min || (max >= 0 && len(valueSlice) > max) {
+					return fmt.Errorf("count %v is out of range %v", len(valueSlice), arg)
+				}
+				return nil
+			},
+		},
+	}
+}
+
+func noAggregatorArg(arg string) error {
+	if len(arg) != 0 {
+		return fmt.Errorf("does not accept argument '%v'", arg)
+	}
+	return nil
+}
+
+func valueToFloat(v *Value) (float64, error) {
+	switch s := v.Scalar.(type) {
+	case int64:
+		return float64(s), nil
+	case float64:
+		return s, nil
+	case string:
+		// 01001、1e3 等文本为了原样输出保留为字符串
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f, nil
+		}
+	default:
+	}
+	return 0, fmt.Errorf("value '%v' is not a number", v.Scalar)
+}
+
+// parseCountRange 解析 min..max，max 省略时返回 -1
+func parseCountRange(arg string) (int, int, error) {
+	if len(arg) == 0 {
+		return 0, 0, fmt.Errorf("count requires range argument like 1..5")
+	}
+	minString, maxString := arg, arg
+	if index := strings.Index(arg, ".."); index != -1 {
+		minString, maxString = arg[:index], arg[index+2:]
+	}
+	min, max := 0, -1
+	var err error
+	if len(minString) != 0 {
+		if min, err = strconv.Atoi(minString); err != nil {
+			return 0, 0, fmt.Errorf("count range '%v' is invalid: %v", arg, err)
+		}
+	}
+	if len(maxString) != 0 {
+		if max, err = strconv.Atoi(maxString); err != nil {
+			return 0, 0, fmt.Errorf("count range '%v' is invalid: %v", arg, err)
+		}
+		if max < min {
+			return 0, 0, fmt.Errorf("count range '%v' max is less than min", arg)
+		}
+	}
+	return min, max, nil
+}
+
+// collectAggregateValue 按注解收集单元格中同一槽位的所有值，同一槽位解析出的值共享注解
+func collectAggregateValue(v *Value, annotationSlice []*Annotation, annotationValueSliceMap map[*Annotation][]*Value) []*Annotation {
+	for _, annotation := range v.AnnotationSlice {
+		if _, isAggregator := aggregatorMap[annotation.Name]; !isAggregator {
+			continue
+		}
+		if _, hasAnnotation := annotationValueSliceMap[annotation]; !hasAnnotation {
+			annotationSlice = append(annotationSlice, annotation)
+		}
+		annotationValueSliceMap[annotation] = append(annotationValueSliceMap[annotation], v)
+	}
+	for _, item := range v.Items {
+		annotationSlice = collectAggregateValue(item, annotationSlice, annotationValueSliceMap)
+	}
+	return annotationSlice
+}
@@ -0,0 +1,59 @@
+package formation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAggregateAnnotation(t *testing.T) {
+	for _, c := range []struct {
+		formation        string
+		content          string
+		errorContainText string
+	}{
+		{"format(A.b,PH:sum(100);A.b,PH)", "1001,60;1002,40", ""},
+		{"format(A.b,PH:sum(100);A.b,PH)", "1001,60;1002,30", "sum 90 is not equal 100"},
+		{"format(A.b,PH:sum(1);A.b,PH)", "1001,0.7;1002,0.3", ""},
+		{"format(A.b,PH:sum(100);A.b,PH)", "1001,60;1002,x", "value 'x' is not a number"},
+		{"format(A.b:unique,PH;A.b,PH)", "1001,1;1002,1", ""},
+		{"format(A.b:unique,PH;A.b,PH)", "1001,1;1001,2", "value 1001 at 0 and 1 is duplicated"},
+		{"format(A.b,PH:increasing;A.b,PH)", "1001,1;1002,2;1003,1e1", ""},
+		{"format(A.b,PH:increasing;A.b,PH)", "1001,1;1002,1", "value 1 at 1 is not greater than 1"},
+		{"format(A.b:count(2..3);A.b)", "1001;1002", ""},
+		{"format(A.b:count(2..3);A.b)", "1001", "count 1 is out of range 2..3"},
+		{"format(A.b:count(..1);A.b)", "1001;1002", "count 2 is out of range ..1"},
+		{"format(A.b:count(2);A.b)", "1001;1002;1003", "count 3 is out of range 2"},
+		{"format(A.b:count(2..);A.b)", "1001;1002;1003", ""},
+		{"format(A.b:unique:count(..2);A.b)", "1001;1001", "is duplicated"},
+		{"format(A.b:unique,A.b)", "1001,1001", ""},
+	} {
+		gameDataJsonObject := &GameDataJsonObject{Format: map[string]int{"x": 0}, Data: [][]interface{}{{c.content}}}
+		checkErrorSlice := NewChecker().Check(testNewFormation(t, c.formation), map[string]*GameDataJsonObject{"A": gameDataJsonObject})
+		if len(c.errorContainText) == 0 {
+			if len(checkErrorSlice) != 0 {
+				t.Errorf("%v content %q Check = %v, expect no error", c.formation, c.content, checkErrorSlice)
+			}
+			continue
+		}
+		if len(checkErrorSlice) != 1 || !strings.Contains(checkErrorSlice[0].Error(), c.errorContainText) {
+			t.Errorf("%v content %q Check = %v, expect error contain %v", c.formation, c.content, checkErrorSlice, c.errorContainText)
+		}
+	}
+}
+
+func TestAggregateAnnotationArg(t *testing.T) {
+	for _, formation := range []string{
+		"format(A.b,PH:sum;A.b,PH)",
+		"format(A.b,PH:sum(x);A.b,PH)",
+		"format(A.b:unique(1);A.b)",
+		"format(A.b:increasing(1);A.b)",
+		"format(A.b:count;A.b)",
+		"format(A.b:count(3..1);A.b)",
+		"format(A.b:count(a..b);A.b)",
+	} {
+		gameDataJsonObject := &GameDataJsonObject{Format: map[string]int{"x": 0}, Data: [][]interface{}{{"1001"}}}
+		if checkErrorSlice := NewChecker().Check(testNewFormation(t, formation), map[string]*GameDataJsonObject{"A": gameDataJsonObject}); len(checkErrorSlice) != 1 {
+			t.Errorf("%v Check = %v, expect 1 argument error", formation, checkErrorSlice)
+		}
+	}
+}
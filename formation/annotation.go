@@ -0,0 +1,69 @@
+package formation
+
+import (
+	"fmt"
+	"go-formation/utility"
+	"regexp"
+	"strings"
+)
+
+// 槽位注解 :name 或 :name(arg)，参数中不能出现括号与冒号
+const annotationExpression = `:[-\w]+(\([^\(\):]*\))?`
+
+var annotationRegexp = regexp.MustCompile(`^(?P<NAME>[-\w]+)(\((?P<ARG>[^\(\):]*)\))?$`)
+
+// 组注解 check(name=arg)，作为逗号列表中不占位置的元素
+var checkRegexp = regexp.MustCompile(`^check\((?P<ARG>[^\(\)]*)\)$`)
+
+const (
+	// ANNOTATION_VALIDATOR 槽位注解 PH:validator(name=arg)，对槽位的值调用 Checker 中注册的 validator
+	ANNOTATION_VALIDATOR = "validator"
+	// ANNOTATION_CHECK 组注解 check(name=arg)，对整组的值调用 Checker 中注册的 validator
+	ANNOTATION_CHECK = "check"
+)
+
+// Annotation 附加在槽位或组上的注解
+type Annotation struct {
+	Name string `json:"Name"`
+	Arg  string `json:"Arg,omitempty"`
+}
+
+func (a *Annotation) String() string {
+	if len(a.Arg) == 0 {
+		return a.Name
+	}
+	return fmt.Sprintf("%v(%v)", a.Name, a.Arg)
+}
+
+// ValidatorNameArg 将 validator 与 check 的参数 name=arg 拆为名字与参数
+func (a *Annotation) ValidatorNameArg() (string, string) {
+	index := strings.IndexRune(a.Arg, '=')
+	if index == -1 {
+		return a.Arg, ""
+	}
+	return a.Arg[:index], a.Arg[index+1:]
+}
+
+// splitSlotAnnotation 将 A.b:name(arg):name 拆为槽位 A.b 与注解
+func splitSlotAnnotation(c string) (string, []*Annotation) {
+	partSlice := strings.Split(c, ":")
+	annotationSlice := make([]*Annotation, 0, len(partSlice)-1)
+	for _, part := range partSlice[1:] {
+		subMatchSlice := annotationRegexp.FindStringSubmatch(part)
+		if len(subMatchSlice) == 0 {
+			utility.GetReporter().Errorf("annotation '%v' in '%v' is invalid", part, c)
+			continue
+		}
+		annotationSlice = append(annotationSlice, &Annotation{Name: subMatchSlice[1], Arg: subMatchSlice[3]})
+	}
+	return partSlice[0], annotationSlice
+}
+
+// parseCheckAnnotation 解析逗号列表中的 check(...) 元素，不是 check 时返回 nil
+func parseCheckAnnotation(c string) *Annotation {
+	subMatchSlice := checkRegexp.FindStringSubmatch(c)
+	if len(subMatchSlice) == 0 {
+		return nil
+	}
+	return &Annotation{Name: ANNOTATION_CHECK, Arg: subMatchSlice[1]}
+}
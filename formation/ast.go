@@ -0,0 +1,290 @@
+package formation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// AST 的版本，节点结构不兼容地变化时递增
+const AST_VERSION = 1
+
+// AST 节点的种类
+const (
+	AST_SEMICOLON     = "semicolon"
+	AST_COMMA         = "comma"
+	AST_FULLSTOP      = "fullstop"
+	AST_PLACEHOLDER   = "placeholder"
+	AST_COLON         = "colon"
+	AST_PERPENDICULAR = "perpendicular"
+	AST_BRACKETS      = "brackets"
+	// AST_CUSTOM 通过 RegisterMatcher 注册的节点，还原时重新解析 Formation
+	AST_CUSTOM = "custom"
+)
+
+// AstNode 节点的 json 形式，供外部工具理解 formation 而不必重新实现 nodeMatcherMap 中的规则
+type AstNode struct {
+	Kind      string `json:"Kind"`
+	Formation string `json:"Formation"`
+	Separator string `json:"Separator,omitempty"`
+	// File Field 引用的 File.field
+	File  string `json:"File,omitempty"`
+	Field string `json:"Field,omitempty"`
+	// Value 括号中分类字段的值
+	Value string `json:"Value,omitempty"`
+	// Repeated 逗号节点的子节点都相同，Children 中只保留一个，表示重复任意次
+	Repeated bool `json:"Repeated,omitempty"`
+	// Annotations 槽位注解，逗号节点为 check(...) 组注解
+	Annotations []*Annotation `json:"Annotations,omitempty"`
+	// Children 分号节点为每组的格式，冒号节点为括号与值，竖线节点为按 Value 排序的分支
+	Children []*AstNode `json:"Children,omitempty"`
+}
+
+// FormationAst 一个 format(...) 单元格的 AST
+type FormationAst struct {
+	Version int      `json:"Version"`
+	File    string   `json:"File"`
+	Field   string   `json:"Field"`
+	Root    *AstNode `json:"Root"`
+	// Nullable NullValues 对应 format(...?) 与 nullable(...)，NullValues 为 null 表示没有声明 nullable
+	Nullable   bool     `json:"Nullable,omitempty"`
+	NullValues []string `json:"NullValues"`
+}
+
+func NewFormationAst(f *Formation) *FormationAst {
+	a := &FormationAst{Version: AST_VERSION, File: f.File, Field: f.Field, Nullable: f.Nullable, NullValues: f.NullValueSlice}
+	if f.HasDecoration {
+		a.Root = NewAstNode(f.DecorationNode)
+	} else {
+		a.Root = NewAstNode(f.FormationNode)
+	}
+	return a
+}
+
+// NewAstNode 将解析出的节点转为 AST
+func NewAstNode(n Node) *AstNode {
+	switch node := n.(type) {
+	case *SemicolonNode:
+		return &AstNode{
+			Kind:      AST_SEMICOLON,
+			Formation: node.Formation,
+			Separator: string(markerRuneMap[SEMICOLON]),
+			Children:  []*AstNode{NewAstNode(node.SubNode)},
+		}
+	case *CommaNode:
+		a := &AstNode{
+			Kind:        AST_COMMA,
+			Formation:   node.Formation,
+			Separator:   string(markerRuneMap[COMMA]),
+			Repeated:    len(node.SubNodeSlice) == 1,
+			Annotations: node.CheckSlice,
+		}
+		for _, subNode := range node.SubNodeSlice {
+			a.Children = append(a.Children, NewAstNode(subNode))
+		}
+		return a
+	case *FullstopNode:
+		if node.IsPlaceHolder {
+			return &AstNode{Kind: AST_PLACEHOLDER, Formation: node.Formation, Annotations: node.AnnotationSlice}
+		}
+		return &AstNode{
+			Kind:        AST_FULLSTOP,
+			Formation:   node.Formation,
+			Separator:   string(markerRuneMap[FULLSTOP]),
+			File:        node.Key,
+			Field:       node.Value,
+			Annotations: node.AnnotationSlice,
+		}
+	case *ColonNode:
+		return &AstNode{
+			Kind:      AST_COLON,
+			Formation: node.Formation,
+			Separator: string(markerRuneMap[COLON]),
+			Children:  []*AstNode{NewAstNode(node.KeyNode), NewAstNode(node.ValueNode)},
+		}
+	case *PerpendicularNode:
+		a := &AstNode{
+			Kind:      AST_PERPENDICULAR,
+			Formation: node.Formation,
+			Separator: string(markerRuneMap[PERPENDICULAR]),
+		}
+		for _, refValue := range sortRefValueSlice(node.RefValueSubFormationMap) {
+			a.Children = append(a.Children, NewAstNode(node.RefValueSubFormationMap[refValue]))
+		}
+		return a
+	case *BracketsNode:
+		return &AstNode{
+			Kind:      AST_BRACKETS,
+			Formation: node.Formation,
+			Separator: "()",
+			Value:     node.Value,
+			Children:  []*AstNode{NewAstNode(node.Key)},
+		}
+	default:
+	}
+	if n == nil {
+		return nil
+	}
+	a := &AstNode{
+		Kind:      AST_CUSTOM,
+		Formation: n.GetFormation(),
+		File:      n.GetKey(),
+		Field:     n.GetValue(),
+	}
+	for _, child := range n.Children() {
+		a.Children = append(a.Children, NewAstNode(child))
+	}
+	return a
+}
+
+// Formation 由 AST 还原 Formation，不重新解析 formation 字符串
+func (a *FormationAst) Formation() (*Formation, error) {
+	if a.Version != AST_VERSION {
+		return nil, fmt.Errorf("ast version %v is not supported, expect %v", a.Version, AST_VERSION)
+	}
+	if a.Root == nil {
+		return nil, fmt.Errorf("%v.%v ast root is nil", a.File, a.Field)
+	}
+	f := &Formation{File: a.File, Field: a.Field, Nullable: a.Nullable, NullValueSlice: a.NullValues}
+	if a.Root.Kind == AST_PERPENDICULAR {
+		decorationNode, err := a.Root.perpendicularNode()
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %v", a.File, a.Field, err)
+		}
+		f.HasDecoration = true
+		f.DecorationNode = decorationNode
+		return f, nil
+	}
+	formationNode, err := a.Root.Node()
+	if err != nil {
+		return nil, fmt.Errorf("%v.%v: %v", a.File, a.Field, err)
+	}
+	f.FormationNode = formationNode
+	return f, nil
+}
+
+// Node 由 AST 还原节点
+func (a *AstNode) Node() (Node, error) {
+	if a == nil {
+		return nil, fmt.Errorf("ast node is nil")
+	}
+	switch a.Kind {
+	case AST_SEMICOLON:
+		if len(a.Children) != 1 {
+			return nil, fmt.Errorf("semicolon ast node '%v' must have 1 child, got %v", a.Formation, len(a.Children))
+		}
+		subNode, err := a.Children[0].Node()
+		if err != nil {
+			return nil, err
+		}
+		return &SemicolonNode{Formation: a.Formation, SubNode: subNode}, nil
+	case AST_COMMA:
+		if len(a.Children) == 0 || (a.Repeated && len(a.Children) != 1) {
+			return nil, fmt.Errorf("comma ast node '%v' has invalid children count %v", a.Formation, len(a.Children))
+		}
+		n := &CommaNode{Formation: a.Formation, CheckSlice: a.Annotations}
+		for _, child := range a.Children {
+			subNode, err := child.Node()
+			if err != nil {
+				return nil, err
+			}
+			n.SubNodeSlice = append(n.SubNodeSlice, subNode)
+		}
+		return n, nil
+	case AST_FULLSTOP:
+		if len(a.File) == 0 || len(a.Field) == 0 {
+			return nil, fmt.Errorf("fullstop ast node '%v' file or field is empty", a.Formation)
+		}
+		return &FullstopNode{BaseNode: BaseNode{Key: a.File, Value: a.Field}, Formation: a.Formation, AnnotationSlice: a.Annotations}, nil
+	case AST_PLACEHOLDER:
+		return &FullstopNode{Formation: a.Formation, IsPlaceHolder: true, AnnotationSlice: a.Annotations}, nil
+	case AST_COLON, AST_BRACKETS, AST_PERPENDICULAR:
+		return a.decorationNode()
+	case AST_CUSTOM:
+		n, err := parseFormation(a.Formation)
+		if err != nil {
+			return nil, fmt.Errorf("custom ast node '%v': %v", a.Formation, err)
+		}
+		return n, nil
+	default:
+	}
+	return nil, fmt.Errorf("ast node '%v' kind '%v' can not convert to node", a.Formation, a.Kind)
+}
+
+// decorationNode 还原分类相关的节点，出错时返回 nil 接口而不是带类型的 nil
+func (a *AstNode) decorationNode() (Node, error) {
+	var n Node
+	var err error
+	switch a.Kind {
+	case AST_COLON:
+		n, err = a.colonNode()
+	case AST_BRACKETS:
+		n, err = a.bracketsNode()
+	default:
+		n, err = a.perpendicularNode()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (a *AstNode) colonNode() (*ColonNode, error) {
+	if a.Kind != AST_COLON || len(a.Children) != 2 {
+		return nil, fmt.Errorf("ast node '%v' is not colon node with 2 children", a.Formation)
+	}
+	keyNode, err := a.Children[0].bracketsNode()
+	if err != nil {
+		return nil, err
+	}
+	valueNode, err := a.Children[1].Node()
+	if err != nil {
+		return nil, err
+	}
+	return &ColonNode{Formation: a.Formation, KeyNode: keyNode, ValueNode: valueNode}, nil
+}
+
+func (a *AstNode) bracketsNode() (*BracketsNode, error) {
+	if a.Kind != AST_BRACKETS || len(a.Children) != 1 || a.Children[0].Kind != AST_FULLSTOP {
+		return nil, fmt.Errorf("ast node '%v' is not brackets node with fullstop child", a.Formation)
+	}
+	keyNode, err := a.Children[0].Node()
+	if err != nil {
+		return nil, err
+	}
+	return &BracketsNode{Formation: a.Formation, Key: keyNode, Value: a.Value}, nil
+}
+
+func (a *AstNode) perpendicularNode() (*PerpendicularNode, error) {
+	if len(a.Children) == 0 {
+		return nil, fmt.Errorf("perpendicular ast node '%v' has no branch", a.Formation)
+	}
+	n := &PerpendicularNode{Formation: a.Formation, RefValueSubFormationMap: make(map[string]*ColonNode)}
+	for _, child := range a.Children {
+		subNode, err := child.colonNode()
+		if err != nil {
+			return nil, err
+		}
+		if _, hasKey := n.RefValueSubFormationMap[subNode.GetKeyRelateValue()]; hasKey {
+			return nil, fmt.Errorf("perpendicular ast node '%v' key '%v' already exists", a.Formation, subNode.GetKeyRelateValue())
+		}
+		n.RefValueSubFormationMap[subNode.GetKeyRelateValue()] = subNode
+		if n.RefKeyFormationNode == nil {
+			n.RefKeyFormationNode = subNode.GetKeyRelateFormationNode()
+			n.Key = n.RefKeyFormationNode.GetKey()
+			n.Value = n.RefKeyFormationNode.GetValue()
+		}
+	}
+	return n, nil
+}
+
+func MarshalFormationAst(f *Formation) ([]byte, error) {
+	return json.Marshal(NewFormationAst(f))
+}
+
+func UnmarshalFormationAst(data []byte) (*Formation, error) {
+	a := &FormationAst{}
+	if err := json.Unmarshal(data, a); err != nil {
+		return nil, err
+	}
+	return a.Formation()
+}
@@ -0,0 +1,70 @@
+package formation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestFormationAstRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		formation string
+		refValue  string
+		content   string
+	}{
+		{"format(A.b)", "", "1001"},
+		{"format(A.b,PH)", "", "1001,10"},
+		{"format(A.b,A.b)", "", "1001,1002,1003"},
+		{"format(A.b,PH;A.b,PH)", "", "1001,10;1002,20"},
+		{"format(A.b:sum(30),PH)", "", "10,20"},
+		{"format(A.t(1):B.c,PH|A.t(2):C.d)", "2", "5"},
+		{"format(A.b?)", "", "1001"},
+		{"format(A.b) nullable(0,-1)", "", "1001"},
+	} {
+		f := testNewFormation(t, c.formation)
+		data, err := MarshalFormationAst(f)
+		if err != nil {
+			t.Errorf("%v MarshalFormationAst error: %v", c.formation, err)
+			continue
+		}
+		restored, err := UnmarshalFormationAst(data)
+		if err != nil {
+			t.Errorf("%v UnmarshalFormationAst(%s) error: %v", c.formation, data, err)
+			continue
+		}
+		if restoredData, _ := MarshalFormationAst(restored); string(restoredData) != string(data) {
+			t.Errorf("%v ast round trip = %s, expect %s", c.formation, restoredData, data)
+		}
+		if !reflect.DeepEqual(restored.GetRelateFileFieldMap(), f.GetRelateFileFieldMap()) {
+			t.Errorf("%v restored relate map = %v, expect %v", c.formation, restored.GetRelateFileFieldMap(), f.GetRelateFileFieldMap())
+		}
+		v, err := f.ParseValue(c.refValue, c.content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restoredValue, err := restored.ParseValue(c.refValue, c.content)
+		if err != nil {
+			t.Errorf("%v restored ParseValue(%q) error: %v", c.formation, c.content, err)
+			continue
+		}
+		expectJson, _ := json.Marshal(v.Interface())
+		if restoredJson, _ := json.Marshal(restoredValue.Interface()); string(restoredJson) != string(expectJson) {
+			t.Errorf("%v restored ParseValue(%q) = %s, expect %s", c.formation, c.content, restoredJson, expectJson)
+		}
+	}
+}
+
+func TestUnmarshalFormationAstError(t *testing.T) {
+	for _, data := range []string{
+		`{"Version":0,"File":"A","Field":"x","Root":{"Kind":"fullstop","Formation":"A.b","File":"A","Field":"b"}}`,
+		`{"Version":1,"File":"A","Field":"x"}`,
+		`{"Version":1,"File":"A","Field":"x","Root":{"Kind":"fullstop","Formation":"A.b"}}`,
+		`{"Version":1,"File":"A","Field":"x","Root":{"Kind":"semicolon","Formation":"A.b;A.b"}}`,
+		`{"Version":1,"File":"A","Field":"x","Root":{"Kind":"unknown","Formation":"A.b"}}`,
+		`not json`,
+	} {
+		if _, err := UnmarshalFormationAst([]byte(data)); err == nil {
+			t.Errorf("UnmarshalFormationAst(%s) expect error", data)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package formation
+
+import (
+	"fmt"
+	"sort"
+)
+
+// CheckOption 控制 CheckGameData 执行的检查
+type CheckOption struct {
+	// Target 不为空时按 RelationCheckForTarget 只检查导出到 Target 的字段
+	Target string
+	// Checker 执行带注解的 formation 中的 validator 与 sum、unique 等聚合注解，为 nil 时使用 NewChecker()，
+	// 即只执行内置的聚合注解，调用的 validator 没有注册时报告错误
+	Checker *Checker
+}
+
+// CheckGameData 关联检查的入口，fileFormationMap 为表名到每列 formation 行的单元格，即导出时返回的 formation：
+// 检查每张表的主键唯一、rules(...) 声明的列规则、每个 formation 引用的值存在以及注解，返回的 warningSlice 为引用了非主键字段的警告
+func CheckGameData(gameDataJsonObjectMap map[string]*GameDataJsonObject, fileFormationMap map[string]map[string]string, option *CheckOption) ([]error, []error) {
+	if option == nil {
+		option = &CheckOption{}
+	}
+	checker := option.Checker
+	if checker == nil {
+		checker = NewChecker()
+	}
+	checkErrorSlice := make([]error, 0)
+	warningSlice := make([]error, 0)
+
+	for _, file := range sortedKeySlice(gameDataJsonObjectMap) {
+		for _, err := range gameDataJsonObjectMap[file].CheckPrimaryKey() {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("file %v: %v", file, err))
+		}
+	}
+
+	for _, file := range sortedFileSlice(fileFormationMap) {
+		formationMap := fileFormationMap[file]
+		fieldSlice := make([]string, 0, len(formationMap))
+		for field := range formationMap {
+			fieldSlice = append(fieldSlice, field)
+		}
+		sort.Strings(fieldSlice)
+		for _, field := range fieldSlice {
+			r, err := NewRules(file, field, formationMap[field])
+			if err != nil {
+				checkErrorSlice = append(checkErrorSlice, err)
+			} else if r != nil {
+				checkErrorSlice = append(checkErrorSlice, r.Check(gameDataJsonObjectMap)...)
+			}
+
+			f, err := NewFormation(file, field, formationMap[field])
+			if err != nil {
+				checkErrorSlice = append(checkErrorSlice, err)
+				continue
+			}
+			if f == nil {
+				continue
+			}
+			var relationCheckErrorSlice []error
+			if len(option.Target) != 0 {
+				_, relationCheckErrorSlice = f.RelationCheckForTarget(gameDataJsonObjectMap, option.Target)
+			} else {
+				_, relationCheckErrorSlice = f.RelationCheck(gameDataJsonObjectMap)
+			}
+			checkErrorSlice = append(checkErrorSlice, relationCheckErrorSlice...)
+			if hasAnnotation(f) {
+				checkErrorSlice = append(checkErrorSlice, checker.Check(f, gameDataJsonObjectMap)...)
+			}
+			warningSlice = append(warningSlice, f.PrimaryKeyCheck(gameDataJsonObjectMap)...)
+		}
+	}
+	return checkErrorSlice, warningSlice
+}
+
+// hasAnnotation 判断 formation 中是否有槽位注解或组注解
+func hasAnnotation(f *Formation) bool {
+	isAnnotated := false
+	Inspect(f.Root(), func(node Node) bool {
+		switch n := node.(type) {
+		case *FullstopNode:
+			isAnnotated = isAnnotated || len(n.AnnotationSlice) != 0
+		case *CommaNode:
+			isAnnotated = isAnnotated || len(n.CheckSlice) != 0
+		default:
+		}
+		return node != nil && !isAnnotated
+	})
+	return isAnnotated
+}
+
+func sortedKeySlice(gameDataJsonObjectMap map[string]*GameDataJsonObject) []string {
+	keySlice := make([]string, 0, len(gameDataJsonObjectMap))
+	for key := range gameDataJsonObjectMap {
+		keySlice = append(keySlice, key)
+	}
+	sort.Strings(keySlice)
+	return keySlice
+}
+
+func sortedFileSlice(fileFormationMap map[string]map[string]string) []string {
+	fileSlice := make([]string, 0, len(fileFormationMap))
+	for file := range fileFormationMap {
+		fileSlice = append(fileSlice, file)
+	}
+	sort.Strings(fileSlice)
+	return fileSlice
+}
@@ -0,0 +1,158 @@
+package formation
+
+import (
+	"go-formation/utility"
+	"strings"
+	"testing"
+)
+
+func testGameDataJsonObject(t *testing.T, jsonString string) *GameDataJsonObject {
+	gameDataJsonObject, err := LoadGameDataJsonObject(strings.NewReader(jsonString))
+	if err != nil {
+		t.Fatalf("json %v: %v", jsonString, err)
+	}
+	return gameDataJsonObject
+}
+
+// testCsvGameData 按默认表头布局导出 CSV，返回导出的 json 与 formation 行
+func testCsvGameData(t *testing.T, name, content string) (*GameDataJsonObject, map[string]string) {
+	jsonString, formationMap, err := utility.ConvertFileContentToJsonWithName(name, strings.NewReader(content))
+	if err != nil {
+		t.Fatalf("export %v: %v", name, err)
+	}
+	return testGameDataJsonObject(t, jsonString), formationMap
+}
+
+func TestCheckGameData(t *testing.T) {
+	defer utility.SetDefaultNullValues(utility.LEGACY_NULL_VALUES)
+	for _, c := range []struct {
+		name             string
+		itemJson         string
+		rewardJson       string
+		formation        string
+		strict           bool
+		errorCount       int
+		warningCount     int
+		errorContainText string
+	}{
+		{"ok", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"],[1002,"b"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[["1001"],["1002"]]}`, "format(Item.id)", false, 0, 0, ""},
+		{"missing reference", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[["1001"],["1003"]]}`, "format(Item.id)", false, 1, 0, "1003"},
+		{"duplicated primary key", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"],[1001,"b"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[["1001"]]}`, "format(Item.id)", false, 1, 0, "duplicated"},
+		{"not primary key", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[["a"]]}`, "format(Item.name)", false, 0, 1, ""},
+		{"required empty", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[[1001],[null]]}`, "format(Item.id)", true, 1, 0, "row 1: required field is empty"},
+		{"nullable empty", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[[1001],[null]]}`, "format(Item.id?)", false, 0, 0, ""},
+		{"legacy empty is null", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[[1001],[null]]}`, "format(Item.id)", false, 0, 0, ""},
+		{"legacy zero is null", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[[1001],[0],[-1]]}`, "format(Item.id)", false, 0, 0, ""},
+		{"zero is a reference", `{"Format":{"id":0,"name":1},"Data":[[1001,"a"]],"Keys":["id"]}`, `{"Format":{"item":0},"Data":[[1001],[0]]}`, "format(Item.id)", true, 1, 0, "can not find content 0"},
+	} {
+		gameDataJsonObjectMap := map[string]*GameDataJsonObject{
+			"Item":   testGameDataJsonObject(t, c.itemJson),
+			"Reward": testGameDataJsonObject(t, c.rewardJson),
+		}
+		fileFormationMap := map[string]map[string]string{
+			"Item":   {"id": "", "name": ""},
+			"Reward": {"item": c.formation},
+		}
+		utility.SetDefaultNullValues(utility.LEGACY_NULL_VALUES)
+		if c.strict {
+			utility.SetDefaultNullValues([]string{})
+		}
+		checkErrorSlice, warningSlice := CheckGameData(gameDataJsonObjectMap, fileFormationMap, nil)
+		if len(checkErrorSlice) != c.errorCount || len(warningSlice) != c.warningCount {
+			t.Errorf("%v: CheckGameData errors = %v, warnings = %v", c.name, checkErrorSlice, warningSlice)
+			continue
+		}
+		if len(c.errorContainText) != 0 && !strings.Contains(checkErrorSlice[0].Error(), c.errorContainText) {
+			t.Errorf("%v: CheckGameData error = %v, expect contain %v", c.name, checkErrorSlice[0], c.errorContainText)
+		}
+	}
+}
+
+func TestCheckGameDataForTarget(t *testing.T) {
+	gameDataJsonObjectMap := map[string]*GameDataJsonObject{
+		"Item":   testGameDataJsonObject(t, `{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["server"]}}`),
+		"Reward": testGameDataJsonObject(t, `{"Format":{"item":0},"Data":[["1001"]],"Export":{"item":["server","client"]}}`),
+	}
+	fileFormationMap := map[string]map[string]string{
+		"Reward": {"item": "format(Item.id)"},
+	}
+	if checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, &CheckOption{Target: "server"}); len(checkErrorSlice) != 0 {
+		t.Errorf("CheckGameData server errors = %v", checkErrorSlice)
+	}
+	if checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, &CheckOption{Target: "client"}); len(checkErrorSlice) != 1 {
+		t.Errorf("CheckGameData client errors = %v, expect 1", checkErrorSlice)
+	}
+}
+
+func TestCheckGameDataTypes(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		itemJson         string
+		rewardJson       string
+		errorContainText string
+	}{
+		{"int key with leading zero", `{"Format":{"id":0},"Data":[[1001]],"Types":{"id":"int"}}`, `{"Format":{"item":0},"Data":[["01001"]],"Types":{"item":"string"}}`, ""},
+		{"int key from float content", `{"Format":{"id":0},"Data":[[1001]],"Types":{"id":"int"}}`, `{"Format":{"item":0},"Data":[[1001.0]],"Types":{"item":"float"}}`, ""},
+		{"int key from fractional content", `{"Format":{"id":0},"Data":[[1001]],"Types":{"id":"int"}}`, `{"Format":{"item":0},"Data":[[1001.5]],"Types":{"item":"float"}}`, "is incompatible with content 1001.5"},
+		{"int content to float key", `{"Format":{"id":0},"Data":[[1000000.0]],"Types":{"id":"float"}}`, `{"Format":{"item":0},"Data":[[1000000]],"Types":{"item":"int"}}`, ""},
+		{"large int key", `{"Format":{"id":0},"Data":[[9007199254740993]],"Types":{"id":"int64"}}`, `{"Format":{"item":0},"Data":[[9007199254740993]],"Types":{"item":"int64"}}`, ""},
+		{"large int key missing", `{"Format":{"id":0},"Data":[[9007199254740993]],"Types":{"id":"int64"}}`, `{"Format":{"item":0},"Data":[[9007199254740992]],"Types":{"item":"int64"}}`, "can not find content 9007199254740992"},
+		{"string key", `{"Format":{"id":0},"Data":[["01001"]],"Types":{"id":"string"}}`, `{"Format":{"item":0},"Data":[["1001"]],"Types":{"item":"string"}}`, "can not find content 1001"},
+		{"untyped key", `{"Format":{"id":0},"Data":[[1001]]}`, `{"Format":{"item":0},"Data":[["1001.0"]]}`, ""},
+		{"incompatible content", `{"Format":{"id":0},"Data":[[1001]],"Types":{"id":"int"}}`, `{"Format":{"item":0},"Data":[["abc"]]}`, "is incompatible with content abc"},
+	} {
+		gameDataJsonObjectMap := map[string]*GameDataJsonObject{
+			"Item":   testGameDataJsonObject(t, c.itemJson),
+			"Reward": testGameDataJsonObject(t, c.rewardJson),
+		}
+		fileFormationMap := map[string]map[string]string{"Reward": {"item": "format(Item.id)"}}
+		checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, nil)
+		if len(c.errorContainText) == 0 {
+			if len(checkErrorSlice) != 0 {
+				t.Errorf("%v: CheckGameData errors = %v, expect none", c.name, checkErrorSlice)
+			}
+			continue
+		}
+		if len(checkErrorSlice) == 0 || !strings.Contains(checkErrorSlice[0].Error(), c.errorContainText) {
+			t.Errorf("%v: CheckGameData errors = %v, expect contain %v", c.name, checkErrorSlice, c.errorContainText)
+		}
+	}
+}
+
+func TestCheckExportedGameData(t *testing.T) {
+	itemCsv := "comment,comment\n,\nserver,server\nid,name\nint,string\n01001,a\n010,b\n0x20,c\n"
+	// 引用整数主键的列可以是整数列，也可以是值都能转为整数的字符串列
+	for _, c := range []struct {
+		itemType         string
+		item             string
+		errorContainText string
+	}{
+		{"int", "1001", ""},
+		{"int", "01001", ""},
+		{"int", "10", ""},
+		{"int", "010", ""},
+		{"int", "32", ""},
+		{"int", "8", "can not find content 8"},
+		{"string", "01001", ""},
+		{"string", "010", ""},
+		{"string", "0x20", ""},
+		{"string", "8", "can not find content 8"},
+		{"string", "abc", "is incompatible with content abc"},
+	} {
+		rewardCsv := "comment,comment\n,format(Item.id)\nserver,server\nid,item\nint," + c.itemType + "\n1,\"" + c.item + "\"\n"
+		itemGameDataJsonObject, itemFormationMap := testCsvGameData(t, "Item", itemCsv)
+		rewardGameDataJsonObject, rewardFormationMap := testCsvGameData(t, "Reward", rewardCsv)
+		gameDataJsonObjectMap := map[string]*GameDataJsonObject{"Item": itemGameDataJsonObject, "Reward": rewardGameDataJsonObject}
+		fileFormationMap := map[string]map[string]string{"Item": itemFormationMap, "Reward": rewardFormationMap}
+		checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, nil)
+		if len(c.errorContainText) == 0 {
+			if len(checkErrorSlice) != 0 {
+				t.Errorf("%v %q: CheckGameData errors = %v, expect none", c.itemType, c.item, checkErrorSlice)
+			}
+			continue
+		}
+		if len(checkErrorSlice) != 1 || !strings.Contains(checkErrorSlice[0].Error(), c.errorContainText) {
+			t.Errorf("%v %q: CheckGameData errors = %v, expect contain %v", c.itemType, c.item, checkErrorSlice, c.errorContainText)
+		}
+	}
+}
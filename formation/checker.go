@@ -0,0 +1,168 @@
+package formation
+
+import (
+	"fmt"
+	"go-formation/utility"
+	"sort"
+)
+
+// ValidatorContext 调用 validator 时的上下文
+type ValidatorContext struct {
+	Formation *Formation
+	// Table 单元格所在的表，TableMap 为所有表，用于跨表检查
+	Table    *GameDataJsonObject
+	TableMap map[string]*GameDataJsonObject
+	Row      int
+	// RowDataSlice 单元格所在的行，按 Table.Format 排列
+	RowDataSlice []interface{}
+	// Content 整个单元格，Cell 为其值树
+	Content string
+	Cell    *Value
+	// Value 注解所在的槽位或组的值
+	Value *Value
+	// Arg validator(name=arg) 中的 arg
+	Arg string
+}
+
+// Validator 检查不通过时返回错误
+type Validator func(ctx *ValidatorContext) error
+
+// Checker 执行 formation 中 validator(...) 与 check(...) 注解调用的 validator，以及 sum、unique 等聚合注解
+type Checker struct {
+	validatorMap map[string]Validator
+}
+
+func NewChecker() *Checker {
+	return &Checker{validatorMap: make(map[string]Validator)}
+}
+
+// RegisterValidator 注册 validator，同名的 validator 只能注册一次
+func (c *Checker) RegisterValidator(name string, v Validator) error {
+	if v == nil {
+		return fmt.Errorf("validator %v is nil", name)
+	}
+	if _, hasValidator := c.validatorMap[name]; hasValidator {
+		return fmt.Errorf("validator %v already registered", name)
+	}
+	c.validatorMap[name] = v
+	return nil
+}
+
+// GetValidatorNameSlice 返回已注册的 validator 名字
+func (c *Checker) GetValidatorNameSlice() []string {
+	validatorNameSlice := make([]string, 0, len(c.validatorMap))
+	for name := range c.validatorMap {
+		validatorNameSlice = append(validatorNameSlice, name)
+	}
+	sort.Strings(validatorNameSlice)
+	return validatorNameSlice
+}
+
+// Check 对表中每一行的单元格执行 formation 中的注解，空单元格不检查
+func (c *Checker) Check(f *Formation, gameDataJsonObjectMap map[string]*GameDataJsonObject) []error {
+	gameDataJsonObject, hasGameDataJsonObject := gameDataJsonObjectMap[f.File]
+	if gameDataJsonObject == nil || !hasGameDataJsonObject {
+		return []error{fmt.Errorf("file %v game data json object is nil", f.File)}
+	}
+	if checkErrorSlice := c.checkAnnotation(f); len(checkErrorSlice) != 0 {
+		return checkErrorSlice
+	}
+
+	checkErrorSlice := make([]error, 0)
+	for row, rowDataSlice := range gameDataJsonObject.Data {
+		cell, err := f.ParseRowValue(gameDataJsonObject.Format, rowDataSlice)
+		if err != nil {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("row %v: %v", row, err))
+			continue
+		}
+		if cell == nil {
+			continue
+		}
+		ctx := &ValidatorContext{
+			Formation:    f,
+			Table:        gameDataJsonObject,
+			TableMap:     gameDataJsonObjectMap,
+			Row:          row,
+			RowDataSlice: rowDataSlice,
+			Content:      utility.CellContent(rowDataSlice[gameDataJsonObject.Format[f.Field]]),
+			Cell:         cell,
+		}
+		checkErrorSlice = append(checkErrorSlice, c.checkValue(ctx, cell)...)
+		checkErrorSlice = append(checkErrorSlice, c.checkAggregate(ctx)...)
+	}
+	return checkErrorSlice
+}
+
+// checkAnnotation 在检查数据前确认 formation 中的注解都能执行
+func (c *Checker) checkAnnotation(f *Formation) []error {
+	checkErrorSlice := make([]error, 0)
+	Inspect(f.Root(), func(node Node) bool {
+		var annotationSlice []*Annotation
+		switch n := node.(type) {
+		case *FullstopNode:
+			annotationSlice = n.AnnotationSlice
+		case *CommaNode:
+			annotationSlice = n.CheckSlice
+		default:
+		}
+		for _, annotation := range annotationSlice {
+			if err := c.checkAnnotationName(annotation); err != nil {
+				checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v.%v formation '%v': %v", f.File, f.Field, node.GetFormation(), err))
+			}
+		}
+		return node != nil
+	})
+	return checkErrorSlice
+}
+
+func (c *Checker) checkAnnotationName(annotation *Annotation) error {
+	switch annotation.Name {
+	case ANNOTATION_VALIDATOR, ANNOTATION_CHECK:
+		name, _ := annotation.ValidatorNameArg()
+		if _, hasValidator := c.validatorMap[name]; !hasValidator {
+			return fmt.Errorf("validator '%v' is not registered", name)
+		}
+		return nil
+	default:
+	}
+	if aggregator, isAggregator := aggregatorMap[annotation.Name]; isAggregator {
+		if err := aggregator.CheckArg(annotation.Arg); err != nil {
+			return fmt.Errorf("annotation %v: %v", annotation.Name, err)
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown annotation '%v'", annotation.Name)
+}
+
+// checkValue 先检查子节点再检查自身的注解
+func (c *Checker) checkValue(ctx *ValidatorContext, v *Value) []error {
+	checkErrorSlice := make([]error, 0)
+	for _, item := range v.Items {
+		checkErrorSlice = append(checkErrorSlice, c.checkValue(ctx, item)...)
+	}
+	for _, annotation := range v.AnnotationSlice {
+		if annotation.Name != ANNOTATION_VALIDATOR && annotation.Name != ANNOTATION_CHECK {
+			continue
+		}
+		name, arg := annotation.ValidatorNameArg()
+		validatorCtx := *ctx
+		validatorCtx.Value = v
+		validatorCtx.Arg = arg
+		if err := c.validatorMap[name](&validatorCtx); err != nil {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v.%v row %v %v on %v: %v", ctx.Formation.File, ctx.Formation.Field, ctx.Row, annotation, v.Interface(), err))
+		}
+	}
+	return checkErrorSlice
+}
+
+// checkAggregate 对单元格中每个带聚合注解的槽位的所有值执行检查
+func (c *Checker) checkAggregate(ctx *ValidatorContext) []error {
+	checkErrorSlice := make([]error, 0)
+	annotationValueSliceMap := make(map[*Annotation][]*Value)
+	for _, annotation := range collectAggregateValue(ctx.Cell, nil, annotationValueSliceMap) {
+		if err := aggregatorMap[annotation.Name].Check(annotation.Arg, annotationValueSliceMap[annotation]); err != nil {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v.%v row %v %v on '%v': %v", ctx.Formation.File, ctx.Formation.Field, ctx.Row, annotation, ctx.Content, err))
+		}
+	}
+	return checkErrorSlice
+}
@@ -0,0 +1,106 @@
+package formation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRewardJson = `{"Format":{"id":0,"reward":1},"Data":[[1,"1001,60;1002,40"],[2,"1001,0;1001,30"],[3,""]]}`
+
+func TestCheckerValidator(t *testing.T) {
+	checker := NewChecker()
+	contextSlice := make([]string, 0)
+	if err := checker.RegisterValidator("positive", func(ctx *ValidatorContext) error {
+		contextSlice = append(contextSlice, fmt.Sprintf("%v:%v:%v", ctx.Row, ctx.Content, ctx.Value.Interface()))
+		if f, err := valueToFloat(ctx.Value); err != nil || f <= 0 {
+			return fmt.Errorf("value is not positive")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checker.RegisterValidator("group_weight", func(ctx *ValidatorContext) error {
+		if fmt.Sprintf("%v", ctx.Value.Item("ph1").Scalar) == ctx.Arg {
+			return fmt.Errorf("weight is %v", ctx.Arg)
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checker.RegisterValidator("positive", func(ctx *ValidatorContext) error { return nil }); err == nil {
+		t.Errorf("RegisterValidator with registered name expect error")
+	}
+	if err := checker.RegisterValidator("nil", nil); err == nil {
+		t.Errorf("RegisterValidator nil validator expect error")
+	}
+	if nameSlice := checker.GetValidatorNameSlice(); !reflect.DeepEqual(nameSlice, []string{"group_weight", "positive"}) {
+		t.Errorf("GetValidatorNameSlice = %v", nameSlice)
+	}
+
+	gameDataJsonObjectMap := map[string]*GameDataJsonObject{"Reward": testGameDataJsonObject(t, testRewardJson)}
+	for _, c := range []struct {
+		formation        string
+		errorContainText []string
+	}{
+		{"format(A.b,PH:validator(positive);A.b,PH)", []string{"row 1 validator(positive) on 0: value is not positive"}},
+		{"format(A.b,PH,check(group_weight=30);A.b,PH,check(group_weight=30))", []string{"row 1 check(group_weight=30)"}},
+		{"format(A.b,PH:validator(missing);A.b,PH)", []string{"validator 'missing' is not registered"}},
+		{"format(A.b:nope,PH;A.b,PH)", []string{"unknown annotation 'nope'"}},
+	} {
+		contextSlice = contextSlice[:0]
+		checkErrorSlice := checker.Check(testNewFormationOf(t, "Reward", "reward", c.formation), gameDataJsonObjectMap)
+		if len(checkErrorSlice) != len(c.errorContainText) {
+			t.Errorf("%v Check = %v, expect %v errors", c.formation, checkErrorSlice, len(c.errorContainText))
+			continue
+		}
+		for i, text := range c.errorContainText {
+			if !strings.Contains(checkErrorSlice[i].Error(), text) {
+				t.Errorf("%v Check error = %v, expect contain %v", c.formation, checkErrorSlice[i], text)
+			}
+		}
+	}
+
+	contextSlice = contextSlice[:0]
+	checker.Check(testNewFormationOf(t, "Reward", "reward", "format(A.b,PH:validator(positive);A.b,PH)"), gameDataJsonObjectMap)
+	if expect := []string{"0:1001,60;1002,40:60", "0:1001,60;1002,40:40", "1:1001,0;1001,30:0", "1:1001,0;1001,30:30"}; !reflect.DeepEqual(contextSlice, expect) {
+		t.Errorf("validator context = %q, expect %q", contextSlice, expect)
+	}
+}
+
+func TestCheckGameDataChecker(t *testing.T) {
+	gameDataJsonObjectMap := map[string]*GameDataJsonObject{
+		"Item":   testGameDataJsonObject(t, `{"Format":{"id":0},"Data":[[1001],[1002]]}`),
+		"Reward": testGameDataJsonObject(t, testRewardJson),
+	}
+	positiveChecker := NewChecker()
+	if err := positiveChecker.RegisterValidator("positive", func(ctx *ValidatorContext) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name             string
+		formation        string
+		option           *CheckOption
+		errorContainText []string
+	}{
+		{"nil option runs aggregate", "format(Item.id,PH:sum(100);Item.id,PH?)", nil, []string{"sum 30 is not equal 100"}},
+		{"nil checker runs aggregate", "format(Item.id,PH:sum(100);Item.id,PH?)", &CheckOption{Target: ""}, []string{"sum 30 is not equal 100"}},
+		{"explicit checker", "format(Item.id,PH:sum(100);Item.id,PH?)", &CheckOption{Checker: NewChecker()}, []string{"sum 30 is not equal 100"}},
+		{"nil option unique", "format(Item.id:unique,PH;Item.id,PH?)", nil, []string{"value 1001 at 0 and 1 is duplicated"}},
+		{"nil option unregistered validator", "format(Item.id,PH:validator(positive);Item.id,PH?)", nil, []string{"validator 'positive' is not registered"}},
+		{"registered validator", "format(Item.id,PH:validator(positive);Item.id,PH?)", &CheckOption{Checker: positiveChecker}, nil},
+	} {
+		fileFormationMap := map[string]map[string]string{"Reward": {"reward": c.formation}}
+		checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, c.option)
+		if len(checkErrorSlice) != len(c.errorContainText) {
+			t.Errorf("%v: CheckGameData = %v, expect %v errors", c.name, checkErrorSlice, len(c.errorContainText))
+			continue
+		}
+		for i, text := range c.errorContainText {
+			if !strings.Contains(checkErrorSlice[i].Error(), text) {
+				t.Errorf("%v: CheckGameData error = %v, expect contain %v", c.name, checkErrorSlice[i], text)
+			}
+		}
+	}
+}
@@ -3,6 +3,7 @@ package formation
 import (
 	"fmt"
 	"go-formation/utility"
+	"strings"
 )
 
 type Formation struct {
@@ -11,6 +12,75 @@ type Formation struct {
 	HasDecoration  bool
 	DecorationNode *PerpendicularNode
 	FormationNode  Node
+	// Nullable format(A.b?) 表示空单元格没有引用
+	Nullable bool
+	// NullValueSlice nullable(0,-1) 声明的表示没有引用的值，nullable() 声明为空的切片，没有声明时为 nil
+	NullValueSlice []string
+}
+
+// NewFormation 解析配置表 formation 行中 format(...) 形式的单元格，单元格中没有 format 时返回 nil
+func NewFormation(file, field, content string) (*Formation, error) {
+	formationValue := TraitFormation(content)
+	if len(formationValue) == 0 {
+		return nil, nil
+	}
+
+	formationValueWithoutSpace, err := TrimSpaceInString(formationValue)
+	if err != nil {
+		return nil, fmt.Errorf("%v.%v trim space in string but occurs error: %v", file, field, err)
+	}
+
+	f := &Formation{
+		File:  file,
+		Field: field,
+	}
+	if strings.HasSuffix(formationValueWithoutSpace, "?") {
+		f.Nullable = true
+		formationValueWithoutSpace = strings.TrimSuffix(formationValueWithoutSpace, "?")
+	}
+	if start, end := traitCallLocation(content, "nullable"); start != -1 {
+		nullableValue, err := TrimSpaceInString(content[start+len("nullable(") : end-1])
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v trim space in string but occurs error: %v", file, field, err)
+		}
+		f.NullValueSlice = make([]string, 0)
+		if len(nullableValue) != 0 {
+			f.NullValueSlice = strings.Split(nullableValue, ",")
+		}
+	}
+	if HasDecoration(formationValueWithoutSpace) {
+		f.HasDecoration = true
+		if f.DecorationNode, err = ParseDecoration(formationValueWithoutSpace); err != nil {
+			return nil, fmt.Errorf("%v.%v %v", file, field, err)
+		}
+	} else {
+		if f.FormationNode, err = ParseFormation(formationValueWithoutSpace); err != nil {
+			return nil, fmt.Errorf("%v.%v %v", file, field, err)
+		}
+	}
+	return f, nil
+}
+
+// IsNull 判断单元格是否表示没有引用，没有引用的单元格不做关联检查，
+// 声明了 ? 或 nullable(...) 的 formation 只按声明判断，否则按项目配置的 utility.GetDefaultNullValues 判断，
+// 默认为 utility.LEGACY_NULL_VALUES，项目配置 "NullValues": [] 开启严格模式，不能为空的单元格为空时返回错误
+func (f *Formation) IsNull(content string) (bool, error) {
+	nullValueSlice := f.NullValueSlice
+	if !f.Nullable && nullValueSlice == nil {
+		nullValueSlice = utility.GetDefaultNullValues()
+	}
+	if f.Nullable && len(content) == 0 {
+		return true, nil
+	}
+	for _, nullValue := range nullValueSlice {
+		if content == nullValue {
+			return true, nil
+		}
+	}
+	if len(content) == 0 {
+		return false, fmt.Errorf("required field is empty")
+	}
+	return false, nil
 }
 
 func (f *Formation) GetRelateFileFieldMap() map[string]string {
@@ -20,6 +90,57 @@ func (f *Formation) GetRelateFileFieldMap() map[string]string {
 	return f.FormationNode.GetRelateFileFieldMap()
 }
 
+// ParseValue 按 formation 将单元格解析为值树，refValue 为分类字段在本行的值，没有分类时忽略
+func (f *Formation) ParseValue(refValue, content string) (*Value, error) {
+	var v *Value
+	var err error
+	if f.HasDecoration {
+		v, err = f.DecorationNode.ParseValueByKey(refValue, content)
+	} else {
+		v, err = f.FormationNode.ParseValue(content)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v.%v content '%v': %v", f.File, f.Field, content, err)
+	}
+	return v, nil
+}
+
+// ParseRowValue 解析按 format 排列的一行数据中 formation 所在的单元格，单元格为空时返回 nil
+func (f *Formation) ParseRowValue(format map[string]int, rowDataSlice []interface{}) (*Value, error) {
+	index, hasField := format[f.Field]
+	if !hasField || index < 0 || index >= len(rowDataSlice) {
+		return nil, fmt.Errorf("%v.%v index %v is invalid for row width %v", f.File, f.Field, index, len(rowDataSlice))
+	}
+	content := utility.CellContent(rowDataSlice[index])
+	if len(content) == 0 {
+		return nil, nil
+	}
+	var refValue string
+	if f.HasDecoration {
+		refIndex, hasRefField := format[f.DecorationNode.RefKeyFormationNode.GetValue()]
+		if !hasRefField || refIndex < 0 || refIndex >= len(rowDataSlice) {
+			return nil, fmt.Errorf("%v.%v reference field %v index %v is invalid for row width %v", f.File, f.Field, f.DecorationNode.RefKeyFormationNode.GetValue(), refIndex, len(rowDataSlice))
+		}
+		refValue = utility.CellContent(rowDataSlice[refIndex])
+	}
+	return f.ParseValue(refValue, content)
+}
+
+// Format 将值树按 formation 输出为单元格内容，refValue 为分类字段在本行的值，没有分类时忽略
+func (f *Formation) Format(refValue string, v *Value) (string, error) {
+	var content string
+	var err error
+	if f.HasDecoration {
+		content, err = f.DecorationNode.FormatByKey(refValue, v)
+	} else {
+		content, err = f.FormationNode.Format(v)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%v.%v: %v", f.File, f.Field, err)
+	}
+	return content, nil
+}
+
 func (f *Formation) RelationCheck(gameDataJsonObjectMap map[string]*GameDataJsonObject) (bool, []error) {
 	gameDataJsonObject, hasGameDataJsonObject := gameDataJsonObjectMap[f.File]
 	if gameDataJsonObject == nil || !hasGameDataJsonObject {
@@ -37,6 +158,59 @@ func (f *Formation) RelationCheck(gameDataJsonObjectMap map[string]*GameDataJson
 	return f.relationCheck(gameDataJsonObjectMap)
 }
 
+// RelationCheckForTarget 只检查导出到 target 的字段，并且要求其引用的字段同样导出到 target
+func (f *Formation) RelationCheckForTarget(gameDataJsonObjectMap map[string]*GameDataJsonObject, target string) (bool, []error) {
+	gameDataJsonObject, hasGameDataJsonObject := gameDataJsonObjectMap[f.File]
+	if gameDataJsonObject == nil || !hasGameDataJsonObject {
+		return false, []error{fmt.Errorf("file %v game data json object is nil", f.File)}
+	}
+	if !gameDataJsonObject.IsExported(f.Field, target) {
+		return true, nil
+	}
+
+	relationCheckErrorSlice := make([]error, 0)
+	for relateFile, relateField := range f.GetRelateFileFieldMap() {
+		relateGameDataJsonObject, hasRelateFile := gameDataJsonObjectMap[relateFile]
+		if relateGameDataJsonObject == nil || !hasRelateFile {
+			continue
+		}
+		if !relateGameDataJsonObject.IsExported(relateField, target) {
+			relationCheckErrorSlice = append(relationCheckErrorSlice, fmt.Errorf("%v.%v exported to %v references %v.%v which is not exported to %v", f.File, f.Field, target, relateFile, relateField, target))
+		}
+	}
+
+	_, checkErrorSlice := f.RelationCheck(gameDataJsonObjectMap)
+	relationCheckErrorSlice = append(relationCheckErrorSlice, checkErrorSlice...)
+	return len(relationCheckErrorSlice) == 0, relationCheckErrorSlice
+}
+
+// PrimaryKeyCheck 返回引用了非主键字段的警告，没有声明主键的表不做检查
+func (f *Formation) PrimaryKeyCheck(gameDataJsonObjectMap map[string]*GameDataJsonObject) []error {
+	relateFileFieldMap := make(map[string]string)
+	if f.HasDecoration {
+		// 分类的键引用的是本行的字段，不是外键
+		for _, subNode := range f.DecorationNode.RefValueSubFormationMap {
+			for filename, field := range subNode.ValueNode.GetRelateFileFieldMap() {
+				relateFileFieldMap[filename] = field
+			}
+		}
+	} else {
+		relateFileFieldMap = f.FormationNode.GetRelateFileFieldMap()
+	}
+
+	warningSlice := make([]error, 0)
+	for relateFile, relateField := range relateFileFieldMap {
+		relateGameDataJsonObject, hasRelateFile := gameDataJsonObjectMap[relateFile]
+		if relateGameDataJsonObject == nil || !hasRelateFile || len(relateGameDataJsonObject.Keys) == 0 {
+			continue
+		}
+		if !relateGameDataJsonObject.IsPrimaryKey(relateField) {
+			warningSlice = append(warningSlice, fmt.Errorf("%v.%v references %v.%v which is not primary key %v", f.File, f.Field, relateFile, relateField, relateGameDataJsonObject.Keys))
+		}
+	}
+	return warningSlice
+}
+
 func (f *Formation) relationWithDecorationCheck(gameDataJsonObjectMap map[string]*GameDataJsonObject) (bool, []error) {
 	gameDataJsonObject := gameDataJsonObjectMap[f.File]
 	checkDataIndex := gameDataJsonObject.Format[f.Field]
@@ -48,6 +222,7 @@ func (f *Formation) relationWithDecorationCheck(gameDataJsonObjectMap map[string
 		f.DecorationNode.RefKeyFormationNode.GetKey(),
 		f.DecorationNode.RefKeyFormationNode.GetValue(),
 		f.DecorationNode.RefValueSubFormationMap,
+		f.IsNull,
 		f.File, f.Field,
 	)
 	relationCheckErrorSlice = append(relationCheckErrorSlice, traitErrorSlice...)
@@ -64,6 +239,7 @@ func traitRelateFileFieldContentSliceMapWithDecorationNode(
 	checkDataIndex int,
 	refFile, refField string,
 	refValueSubFormationMap map[string]*ColonNode,
+	isNull func(content string) (bool, error),
 	traitFile, traitField string,
 ) (map[string]map[string][]string, []error) {
 	relateFileFieldContentSliceMap := make(map[string]map[string][]string)
@@ -73,22 +249,29 @@ func traitRelateFileFieldContentSliceMapWithDecorationNode(
 		return nil, []error{fmt.Errorf("file %v field %v reference file %v field %v game data json object is nil", traitFile, traitField, refFile, refField)}
 	}
 	refIndex, hasRefField := refGameDataJsonObject.Format[refField]
-	if !hasRefField {
+	if refIndex < 0 || !hasRefField {
 		return nil, []error{fmt.Errorf("file %v field %v reference file %v field %v index does not exist in Format %v", traitFile, traitField, refFile, refField, refGameDataJsonObject.Format)}
 	}
 
 	// fmt.Printf("DEBUG: checkDataIndex is = %v, refFile = %v, refField = %v, refIndex = %v, traitFile = %v, traitField = %v\n", checkDataIndex, refFile, refField, refIndex, traitFile, traitField)
 
-	for _, rowDataSlice := range gameDataJsonObject.Data {
-		refValue := fmt.Sprintf("%v", rowDataSlice[refIndex])
-		checkData := fmt.Sprintf("%v", rowDataSlice[checkDataIndex])
+	for row, rowDataSlice := range gameDataJsonObject.Data {
+		if checkDataIndex >= len(rowDataSlice) || refIndex >= len(rowDataSlice) {
+			traitRelateFileFieldContentSliceMapErrorSlice = append(traitRelateFileFieldContentSliceMapErrorSlice, fmt.Errorf("%v.%v row %v width %v is too short, skip", traitFile, traitField, row, len(rowDataSlice)))
+			continue
+		}
+		refValue := utility.CellContent(rowDataSlice[refIndex])
+		checkData := utility.CellContent(rowDataSlice[checkDataIndex])
 		// fmt.Printf("DEBUG: row %v data is %v\n", row, rowDataSlice)
-		if len(checkData) == 0 {
+		if null, err := isNull(checkData); err != nil {
+			traitRelateFileFieldContentSliceMapErrorSlice = append(traitRelateFileFieldContentSliceMapErrorSlice, fmt.Errorf("%v.%v row %v: %v", traitFile, traitField, row, err))
+			continue
+		} else if null {
 			continue
 		}
 		refNode, hasRefNode := refValueSubFormationMap[refValue]
 		if !hasRefNode {
-			fmt.Printf("Error: %v.%v reference value %v from %v.%v does not exist\n", traitFile, traitField, refValue, refFile, refField)
+			utility.GetReporter().Errorf("%v.%v reference value %v from %v.%v does not exist", traitFile, traitField, refValue, refFile, refField)
 			traitRelateFileFieldContentSliceMapErrorSlice = append(traitRelateFileFieldContentSliceMapErrorSlice, fmt.Errorf("%v.%v reference value %v from %v.%v does not exist", traitFile, traitField, refValue, refFile, refField))
 			continue
 		}
@@ -105,7 +288,7 @@ func (f *Formation) relationCheck(gameDataJsonObjectMap map[string]*GameDataJson
 	checkDataIndex := gameDataJsonObject.Format[f.Field]
 	relationCheckErrorSlice := make([]error, 0)
 
-	relateFileFieldContentSliceMap, traitErrorSlice := traitRelateFileFieldContentSliceMap(gameDataJsonObject, checkDataIndex, f.FormationNode, f.File, f.Field)
+	relateFileFieldContentSliceMap, traitErrorSlice := traitRelateFileFieldContentSliceMap(gameDataJsonObject, checkDataIndex, f.FormationNode, f.IsNull, f.File, f.Field)
 	relationCheckErrorSlice = append(relationCheckErrorSlice, traitErrorSlice...)
 
 	// fmt.Printf("DEBUG: relateFileFieldContentSliceMap = %v\n", relateFileFieldContentSliceMap)
@@ -120,15 +303,22 @@ func traitRelateFileFieldContentSliceMap(
 	gameDataJsonObject *GameDataJsonObject,
 	checkDataIndex int,
 	formationNode Node,
+	isNull func(content string) (bool, error),
 	traitFile, traitField string,
 ) (map[string]map[string][]string, []error) {
 	relateFileFieldContentSliceMap := make(map[string]map[string][]string)
 	traitRelateFileFieldContentSliceMapErrorSlice := make([]error, 0)
-	for _, rowDataSlice := range gameDataJsonObject.Data {
+	for row, rowDataSlice := range gameDataJsonObject.Data {
 		// fmt.Printf("DEBUG: row %v data is %v\n", row, rowDataSlice)
-		checkData := fmt.Sprintf("%v", rowDataSlice[checkDataIndex])
-		// NOTE: 零值引用忽略，由空 json 解出来的 int 值为0，需要忽略
-		if len(checkData) == 0 || checkData == "0" || checkData == "-1" {
+		if checkDataIndex >= len(rowDataSlice) {
+			traitRelateFileFieldContentSliceMapErrorSlice = append(traitRelateFileFieldContentSliceMapErrorSlice, fmt.Errorf("%v.%v row %v width %v is too short, skip", traitFile, traitField, row, len(rowDataSlice)))
+			continue
+		}
+		checkData := utility.CellContent(rowDataSlice[checkDataIndex])
+		if null, err := isNull(checkData); err != nil {
+			traitRelateFileFieldContentSliceMapErrorSlice = append(traitRelateFileFieldContentSliceMapErrorSlice, fmt.Errorf("%v.%v row %v: %v", traitFile, traitField, row, err))
+			continue
+		} else if null {
 			// fmt.Printf("DEBUG: row %v continue\n", row)
 			continue
 		}
@@ -158,18 +348,27 @@ func relationCheckHandle(relateFileFieldContentSliceMap map[string]map[string][]
 				relationCheckErrorSlice = append(relationCheckErrorSlice, fmt.Errorf("relate %v.%v index %v is invalid, format = %v", relateFilename, relateField, relateFieldIndex, relateGameDataJsonObject.Format))
 				continue
 			}
+			relateType := relateGameDataJsonObject.Types[relateField]
 			for _, content := range contentSlice {
+				expect, err := utility.NormalizeValue(relateType, content)
+				if err != nil {
+					relationCheckErrorSlice = append(relationCheckErrorSlice, fmt.Errorf("%v.%v type %v is incompatible with content %v: %v", relateFilename, relateField, relateType, content, err))
+					continue
+				}
 				exists := false
 				for _, relateDataSlice := range relateGameDataJsonObject.Data {
+					if relateFieldIndex >= len(relateDataSlice) {
+						continue
+					}
 					// fmt.Printf("DEBUG: check content '%v' from file %v field %v index %v from relateDataSlice '%v', relateDataSlice[%v] = '%v'\n", content, relateFilename, relateField, relateFieldIndex, relateDataSlice, relateFieldIndex, relateDataSlice[relateFieldIndex])
-					if utility.CompareGameDataJsonObjectData(relateDataSlice[relateFieldIndex], content) {
+					if actual, err := utility.NormalizeValue(relateType, relateDataSlice[relateFieldIndex]); err == nil && actual == expect {
 						// fmt.Printf("DEBUG: content '%v' exists\n", content)
 						exists = true
 						break
 					}
 				}
 				if !exists {
-					fmt.Printf("Error: %v.%v can not find content %v\n", relateFilename, relateField, content)
+					utility.GetReporter().Errorf("%v.%v can not find content %v", relateFilename, relateField, content)
 					relationCheckErrorSlice = append(relationCheckErrorSlice, fmt.Errorf("%v.%v can not find content %v", relateFilename, relateField, content))
 					continue
 				}
@@ -199,15 +398,14 @@ func testDecoration() {
 
 	formationValueWithoutSpace, err := TrimSpaceInString(formationValue)
 	if err != nil {
-		fmt.Printf("Error: trim space in string but occurs error: %v\n", err)
+		utility.GetReporter().Errorf("trim space in string but occurs error: %v", err)
 		return
 	}
 
 	if HasDecoration(formationValueWithoutSpace) {
 		formation.HasDecoration = true
-		formation.DecorationNode = ParseDecoration(formationValueWithoutSpace)
-		if formation.DecorationNode == nil {
-			fmt.Printf("Error: formation '%v' has decoration but decoration node is nil\n", formationValueWithoutSpace)
+		formation.DecorationNode, err = ParseDecoration(formationValueWithoutSpace)
+		if err != nil {
 			return
 		}
 
@@ -216,7 +414,7 @@ func testDecoration() {
 		// }
 	} else {
 		formation.HasDecoration = false
-		ParseFormation(formationValueWithoutSpace)
+		formation.FormationNode, _ = ParseFormation(formationValueWithoutSpace)
 	}
 }
 
@@ -0,0 +1,160 @@
+package formation
+
+import (
+	"bytes"
+	"go-formation/utility"
+	"strings"
+	"testing"
+)
+
+func TestNewFormationError(t *testing.T) {
+	defer utility.SetReporter(utility.GetReporter())
+	for _, c := range []struct {
+		formation        string
+		errorContainText string
+	}{
+		{"format(Item.id;Item.id)", ""},
+		{"format(Item.id,PH,check(sum=100);Item.id,PH)", ""},
+		{"format(A.t(1):B.c|A.t(2):C.d,PH)", ""},
+		{"format(Item.id;Itemid)", "can not match sub content 'Itemid'"},
+		{"format(Itemid;Item.id)", "can not match sub content 'Itemid'"},
+		{"format(Item.id,Itemid)", "can not match sub content 'Itemid'"},
+		{"format(Item.id,PH;Item.id,Itemid)", "can not match sub content 'Itemid'"},
+		{"format(Itemid)", "can not match any marker"},
+		{"format(A.t(1):B.c|A.t(2):Cd)", "can not match any marker"},
+		{"format(A.t(1):B.c|A.t(1):C.d)", "key '1' already exists"},
+		{"format(A.t(1):B.c|A.u(2):C.d)", "is not same as relate formation 'A.t'"},
+	} {
+		buffer := &bytes.Buffer{}
+		utility.SetReporter(utility.NewWriterReporter(buffer))
+		f, err := NewFormation("A", "b", c.formation)
+		if len(c.errorContainText) == 0 {
+			if err != nil || f == nil || buffer.Len() != 0 {
+				t.Errorf("NewFormation(%q) = %v, %v, report %q", c.formation, f, err, buffer.String())
+			}
+			continue
+		}
+		if err == nil || f != nil || !strings.Contains(err.Error(), c.errorContainText) {
+			t.Errorf("NewFormation(%q) = %v, %v, expect error contain %v", c.formation, f, err, c.errorContainText)
+			continue
+		}
+		// 错误返回给调用者，Reporter 只是额外的输出
+		if !strings.Contains(buffer.String(), c.errorContainText) {
+			t.Errorf("NewFormation(%q) report %q, expect contain %v", c.formation, buffer.String(), c.errorContainText)
+		}
+	}
+}
+
+func TestIsNull(t *testing.T) {
+	defer utility.SetDefaultNullValues(utility.LEGACY_NULL_VALUES)
+	strictNullValueSlice := []string{}
+	for _, c := range []struct {
+		formation          string
+		defaultNullValues  []string
+		content            string
+		expectNull         bool
+		expectRequiredFail bool
+	}{
+		{"format(A.b)", strictNullValueSlice, "", false, true},
+		{"format(A.b)", strictNullValueSlice, "0", false, false},
+		{"format(A.b)", strictNullValueSlice, "-1", false, false},
+		{"format(A.b)", strictNullValueSlice, "1001", false, false},
+		{"format(A.b?)", strictNullValueSlice, "", true, false},
+		{"format(A.b?)", strictNullValueSlice, "0", false, false},
+		{"format(A.b) nullable(0,-1)", strictNullValueSlice, "", false, true},
+		{"format(A.b) nullable(0,-1)", strictNullValueSlice, "-1", true, false},
+		{"format(A.b) nullable()", utility.LEGACY_NULL_VALUES, "0", false, false},
+		{"format(A.b)", utility.LEGACY_NULL_VALUES, "", true, false},
+		{"format(A.b)", utility.LEGACY_NULL_VALUES, "0", true, false},
+		{"format(A.b)", utility.LEGACY_NULL_VALUES, "-1", true, false},
+		{"format(A.b)", utility.LEGACY_NULL_VALUES, "1001", false, false},
+		{"format(A.b?)", utility.LEGACY_NULL_VALUES, "0", false, false},
+	} {
+		utility.SetDefaultNullValues(c.defaultNullValues)
+		null, err := testNewFormation(t, c.formation).IsNull(c.content)
+		if null != c.expectNull || (err != nil) != c.expectRequiredFail {
+			t.Errorf("%v with default %q IsNull(%q) = %v, %v, expect %v, required fail %v", c.formation, c.defaultNullValues, c.content, null, err, c.expectNull, c.expectRequiredFail)
+		}
+	}
+}
+
+// 没有项目配置时与原来一样跳过空单元格、0 与 -1
+func TestIsNullDefault(t *testing.T) {
+	for _, content := range []string{"", "0", "-1"} {
+		if null, err := testNewFormation(t, "format(A.b)").IsNull(content); !null || err != nil {
+			t.Errorf("default IsNull(%q) = %v, %v, expect null", content, null, err)
+		}
+	}
+	if null, err := testNewFormation(t, "format(A.b)").IsNull("1001"); null || err != nil {
+		t.Errorf("default IsNull(1001) = %v, %v, expect not null", null, err)
+	}
+}
+
+func TestRelationCheckForTarget(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		itemJson         string
+		rewardJson       string
+		target           string
+		errorContainText []string
+	}{
+		{
+			"both exported to server",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["client","server"]}}`,
+			`{"Format":{"item":0},"Data":[[1001]],"Export":{"item":["client","server"]}}`,
+			"server", nil,
+		},
+		{
+			"both exported to client",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["client","server"]}}`,
+			`{"Format":{"item":0},"Data":[[1001]],"Export":{"item":["client","server"]}}`,
+			"client", nil,
+		},
+		{
+			"referenced field only exported to server",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["server"]}}`,
+			`{"Format":{"item":0},"Data":[[1001]],"Export":{"item":["client","server"]}}`,
+			"client", []string{"Reward.item exported to client references Item.id which is not exported to client"},
+		},
+		{
+			"referenced field only exported to server checked for server",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["server"]}}`,
+			`{"Format":{"item":0},"Data":[[1001]],"Export":{"item":["client","server"]}}`,
+			"server", nil,
+		},
+		{
+			"field not exported to target is skipped",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["server"]}}`,
+			`{"Format":{"item":0},"Data":[[1003]],"Export":{"item":["server"]}}`,
+			"client", nil,
+		},
+		{
+			"missing content for target",
+			`{"Format":{"id":0},"Data":[[1001]],"Export":{"id":["client","server"]}}`,
+			`{"Format":{"item":0},"Data":[[1003]],"Export":{"item":["client"]}}`,
+			"client", []string{"can not find content 1003"},
+		},
+		{
+			"no export info",
+			`{"Format":{"id":0},"Data":[[1001]]}`,
+			`{"Format":{"item":0},"Data":[[1001]]}`,
+			"client", nil,
+		},
+	} {
+		gameDataJsonObjectMap := map[string]*GameDataJsonObject{
+			"Item":   testGameDataJsonObject(t, c.itemJson),
+			"Reward": testGameDataJsonObject(t, c.rewardJson),
+		}
+		f := testNewFormationOf(t, "Reward", "item", "format(Item.id)")
+		ok, checkErrorSlice := f.RelationCheckForTarget(gameDataJsonObjectMap, c.target)
+		if ok != (len(c.errorContainText) == 0) || len(checkErrorSlice) != len(c.errorContainText) {
+			t.Errorf("%v: RelationCheckForTarget(%v) = %v, %v, expect %v errors", c.name, c.target, ok, checkErrorSlice, len(c.errorContainText))
+			continue
+		}
+		for i, text := range c.errorContainText {
+			if !strings.Contains(checkErrorSlice[i].Error(), text) {
+				t.Errorf("%v: RelationCheckForTarget(%v) error = %v, expect contain %v", c.name, c.target, checkErrorSlice[i], text)
+			}
+		}
+	}
+}
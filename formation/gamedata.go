@@ -1,6 +1,182 @@
 package formation
 
+import (
+	"encoding/json"
+	"fmt"
+	"go-formation/utility"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
 type GameDataJsonObject struct {
-	Format map[string]int  `json:"Format"`
-	Data   [][]interface{} `json:"Data"`
+	Format map[string]int      `json:"Format"`
+	Data   [][]interface{}     `json:"Data"`
+	Export map[string][]string `json:"Export,omitempty"`
+	Keys   []string            `json:"Keys,omitempty"`
+	// Types 每列在类型行中声明的类型，关联检查按被引用列的类型比较值
+	Types map[string]string `json:"Types,omitempty"`
+}
+
+// IsExported 判断字段是否导出到 target，没有 Export 信息时视为导出到所有目标
+func (o *GameDataJsonObject) IsExported(field, target string) bool {
+	if _, hasField := o.Format[field]; !hasField {
+		return false
+	}
+	if o.Export == nil {
+		return true
+	}
+	for _, t := range o.Export[field] {
+		if t == target {
+			return true
+		}
+	}
+	return false
+}
+
+func LoadGameDataJsonObject(r io.Reader) (*GameDataJsonObject, error) {
+	gameDataJsonObject := &GameDataJsonObject{}
+	decoder := json.NewDecoder(r)
+	// 数字保留为 json.Number，超过 2^53 的 id 不会因为转为 float64 而失去精度
+	decoder.UseNumber()
+	if err := decoder.Decode(gameDataJsonObject); err != nil {
+		return nil, err
+	}
+	return gameDataJsonObject, nil
+}
+
+// LoadGameDataJsonObjectMap 读取目录下所有由 utility.ProcessCsv 导出的 *.json，文件名（不含扩展名）即表名，
+// 无法解析的文件不会出现在返回的 map 中，Validate 不通过的表会被返回并报告错误，检查时跳过其中的错误行
+func LoadGameDataJsonObjectMap(dir string) (map[string]*GameDataJsonObject, []error) {
+	fileInfoSlice, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, []error{err}
+	}
+	gameDataJsonObjectMap := make(map[string]*GameDataJsonObject)
+	loadErrorSlice := make([]error, 0)
+	for _, fileInfo := range fileInfoSlice {
+		if fileInfo.IsDir() || filepath.Ext(fileInfo.Name()) != ".json" {
+			continue
+		}
+		gameDataJsonObject, err := loadGameDataJsonObjectFile(filepath.Join(dir, fileInfo.Name()))
+		if err != nil {
+			loadErrorSlice = append(loadErrorSlice, fmt.Errorf("file %v: %v", fileInfo.Name(), err))
+			continue
+		}
+		for _, validateError := range gameDataJsonObject.Validate() {
+			loadErrorSlice = append(loadErrorSlice, fmt.Errorf("file %v: %v", fileInfo.Name(), validateError))
+		}
+		fileName := utility.TraitFileName(fileInfo.Name(), ".json")
+		if declaredKeySlice := utility.GetPrimaryKeys(fileName); len(declaredKeySlice) != 0 {
+			gameDataJsonObject.Keys = declaredKeySlice
+		}
+		gameDataJsonObjectMap[fileName] = gameDataJsonObject
+	}
+	return gameDataJsonObjectMap, loadErrorSlice
+}
+
+func loadGameDataJsonObjectFile(path string) (*GameDataJsonObject, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadGameDataJsonObject(f)
+}
+
+// Validate 检查 Format 下标唯一且连续（恰好为 0 到 len(Format)-1），并且每一行都有 len(Format) 列，
+// 每个不合法的下标与每个宽度不正确的行各返回一个错误
+func (o *GameDataJsonObject) Validate() []error {
+	if o.Format == nil {
+		return []error{fmt.Errorf("Format does not exist")}
+	}
+	validateErrorSlice := make([]error, 0)
+	width := len(o.Format)
+
+	fieldSlice := make([]string, 0, width)
+	for field := range o.Format {
+		fieldSlice = append(fieldSlice, field)
+	}
+	sort.Strings(fieldSlice)
+	indexFieldMap := make(map[int]string, width)
+	for _, field := range fieldSlice {
+		index := o.Format[field]
+		if index < 0 || index >= width {
+			validateErrorSlice = append(validateErrorSlice, fmt.Errorf("field %v index %v is out of range [0, %v)", field, index, width))
+			continue
+		}
+		if otherField, hasIndex := indexFieldMap[index]; hasIndex {
+			validateErrorSlice = append(validateErrorSlice, fmt.Errorf("field %v index %v is duplicated with field %v", field, index, otherField))
+			continue
+		}
+		indexFieldMap[index] = field
+	}
+	for index := 0; index < width; index++ {
+		if _, hasIndex := indexFieldMap[index]; !hasIndex {
+			validateErrorSlice = append(validateErrorSlice, fmt.Errorf("index %v is not used by any field", index))
+		}
+	}
+
+	for rowIndex, rowDataSlice := range o.Data {
+		if len(rowDataSlice) != width {
+			validateErrorSlice = append(validateErrorSlice, fmt.Errorf("row %v width %v is not equal Format width %v", rowIndex, len(rowDataSlice), width))
+		}
+	}
+	return validateErrorSlice
+}
+
+func (o *GameDataJsonObject) IsPrimaryKey(field string) bool {
+	for _, key := range o.Keys {
+		if key == field {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckPrimaryKey 检查主键（或联合主键）唯一，每个重复的主键返回一个错误，列出其所在的所有行
+func (o *GameDataJsonObject) CheckPrimaryKey() []error {
+	if len(o.Keys) == 0 {
+		return nil
+	}
+	keyIndexSlice := make([]int, 0, len(o.Keys))
+	for _, key := range o.Keys {
+		index, hasKey := o.Format[key]
+		if !hasKey {
+			return []error{fmt.Errorf("primary key %v does not exist in Format %v", key, o.Format)}
+		}
+		keyIndexSlice = append(keyIndexSlice, index)
+	}
+
+	keyValueSlice := make([]string, 0)
+	keyValueRowSliceMap := make(map[string][]int)
+	for row, rowDataSlice := range o.Data {
+		valueSlice := make([]string, 0, len(keyIndexSlice))
+		for _, index := range keyIndexSlice {
+			if index < 0 || index >= len(rowDataSlice) {
+				valueSlice = nil
+				break
+			}
+			valueSlice = append(valueSlice, utility.CellContent(rowDataSlice[index]))
+		}
+		if valueSlice == nil {
+			continue
+		}
+		keyValue := strings.Join(valueSlice, ",")
+		if _, hasKeyValue := keyValueRowSliceMap[keyValue]; !hasKeyValue {
+			keyValueSlice = append(keyValueSlice, keyValue)
+		}
+		keyValueRowSliceMap[keyValue] = append(keyValueRowSliceMap[keyValue], row)
+	}
+
+	checkErrorSlice := make([]error, 0)
+	for _, keyValue := range keyValueSlice {
+		if rowSlice := keyValueRowSliceMap[keyValue]; len(rowSlice) > 1 {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("primary key %v value %v is duplicated in rows %v", strings.Join(o.Keys, ","), keyValue, rowSlice))
+		}
+	}
+	return checkErrorSlice
 }
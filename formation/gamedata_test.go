@@ -0,0 +1,90 @@
+package formation
+
+import (
+	"go-formation/utility"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestLoadGameDataJsonObjectMap(t *testing.T) {
+	defer utility.SetPrimaryKeys("Hero", nil)
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"Item.json":   `{"Format":{"id":0,"name":1},"Data":[[1001,"sword"],[1002,"shield"]],"Keys":["id"]}`,
+		"Reward.json": `{"Format":{"id":0,"item":1},"Data":[[1,1001],[2]]}`,
+		"Hero.json":   `{"Format":{"id":0,"name":1},"Data":[[1,"a"]]}`,
+		"Broken.json": `{"Format":{"id":0},"Data":[[1]`,
+		"readme.txt":  `not a table`,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	utility.SetPrimaryKeys("Hero", []string{"name"})
+
+	gameDataJsonObjectMap, loadErrorSlice := LoadGameDataJsonObjectMap(dir)
+	nameSlice := make([]string, 0, len(gameDataJsonObjectMap))
+	for name := range gameDataJsonObjectMap {
+		nameSlice = append(nameSlice, name)
+	}
+	sort.Strings(nameSlice)
+	// 无法解析的表不返回，Validate 不通过的表仍然返回
+	if expect := []string{"Hero", "Item", "Reward"}; !reflect.DeepEqual(nameSlice, expect) {
+		t.Errorf("LoadGameDataJsonObjectMap tables = %v, expect %v", nameSlice, expect)
+	}
+	if len(loadErrorSlice) != 2 {
+		t.Fatalf("LoadGameDataJsonObjectMap errors = %v, expect 2", loadErrorSlice)
+	}
+	errorString := loadErrorSlice[0].Error() + "\n" + loadErrorSlice[1].Error()
+	for _, text := range []string{"file Broken.json", "file Reward.json: row 1 width 1 is not equal Format width 2"} {
+		if !strings.Contains(errorString, text) {
+			t.Errorf("LoadGameDataJsonObjectMap errors = %v, expect contain %v", loadErrorSlice, text)
+		}
+	}
+	if item := gameDataJsonObjectMap["Item"]; len(item.Data) != 2 || !reflect.DeepEqual(item.Keys, []string{"id"}) {
+		t.Errorf("Item = %+v", item)
+	}
+	if hero := gameDataJsonObjectMap["Hero"]; !reflect.DeepEqual(hero.Keys, []string{"name"}) {
+		t.Errorf("Hero Keys = %v, expect project primary keys [name]", hero.Keys)
+	}
+
+	if _, loadErrorSlice := LoadGameDataJsonObjectMap(filepath.Join(dir, "missing")); len(loadErrorSlice) != 1 {
+		t.Errorf("LoadGameDataJsonObjectMap missing dir errors = %v, expect 1", loadErrorSlice)
+	}
+}
+
+func TestGameDataJsonObjectValidate(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		jsonString       string
+		errorContainText []string
+	}{
+		{"valid", `{"Format":{"id":0,"name":1},"Data":[[1,"a"],[2,"b"]]}`, nil},
+		{"empty", `{"Format":{},"Data":[]}`, nil},
+		{"no format", `{"Data":[[1]]}`, []string{"Format does not exist"}},
+		{"negative index", `{"Format":{"id":-1,"name":1},"Data":[]}`, []string{"field id index -1 is out of range [0, 2)", "index 0 is not used by any field"}},
+		{"index out of range", `{"Format":{"id":0,"name":2},"Data":[]}`, []string{"field name index 2 is out of range [0, 2)", "index 1 is not used by any field"}},
+		{"duplicated index", `{"Format":{"id":0,"name":0},"Data":[]}`, []string{"field name index 0 is duplicated with field id", "index 1 is not used by any field"}},
+		{"short row", `{"Format":{"id":0,"name":1},"Data":[[1,"a"],[2]]}`, []string{"row 1 width 1 is not equal Format width 2"}},
+		{"long row", `{"Format":{"id":0},"Data":[[1,"a"],[2],[3,"c"]]}`, []string{"row 0 width 2 is not equal Format width 1", "row 2 width 2 is not equal Format width 1"}},
+	} {
+		validateErrorSlice := testGameDataJsonObject(t, c.jsonString).Validate()
+		if len(validateErrorSlice) != len(c.errorContainText) {
+			t.Errorf("%v: Validate = %v, expect %v errors", c.name, validateErrorSlice, len(c.errorContainText))
+			continue
+		}
+		for i, text := range c.errorContainText {
+			if !strings.Contains(validateErrorSlice[i].Error(), text) {
+				t.Errorf("%v: Validate error = %v, expect contain %v", c.name, validateErrorSlice[i], text)
+			}
+		}
+	}
+}
@@ -2,24 +2,51 @@ package formation
 
 import (
 	"fmt"
+	"go-formation/utility"
 	"regexp"
 )
 
-var markerRuneMap map[MarkerType]rune
-var nodeMatcherMap map[MarkerType]*Matcher
+var markerRuneMap = make(map[MarkerType]rune)
+var nodeMatcherMap = make(map[MarkerType]*Matcher)
 
-func init() {
-	markerRuneMap = map[MarkerType]rune{
-		COMMA:         ',',
-		FULLSTOP:      '.',
-		COLON:         ':',
-		SEMICOLON:     ';',
-		PERPENDICULAR: '|',
-	}
+// 内置节点的优先级，分类（竖线）最高，占位符最低
+const (
+	PRIORITY_PERPENDICULAR = 600
+	PRIORITY_COLON         = 500
+	PRIORITY_SEMICOLON     = 400
+	PRIORITY_COMMA         = 300
+	PRIORITY_BRACKETS      = 200
+	PRIORITY_FULLSTOP      = 100
+	PRIORITY_PLACEHOLDER   = 50
+)
 
-	nodeMatcherMap = map[MarkerType]*Matcher{
-		SEMICOLON: {
-			t: SEMICOLON,
+func init() {
+	for _, m := range []*Matcher{
+		{
+			Type:      PERPENDICULAR,
+			Priority:  PRIORITY_PERPENDICULAR,
+			Separator: '|',
+			CanMatch:  HasDecoration,
+			NewNode: func() Node {
+				return &PerpendicularNode{}
+			},
+		},
+		{
+			Type:      COLON,
+			Priority:  PRIORITY_COLON,
+			Separator: ':',
+			CanMatch: func(s string) bool {
+				// 键必须是 File.field(value)，只按第一个冒号拆分，值中可以有槽位注解
+				return regexp.MustCompile(`(?ms)^(?P<KEY>[-_\w]+\.[-_\w]+\([^\(\)\s]+\)):(?P<VALUE>\S+)$`).MatchString(s)
+			},
+			NewNode: func() Node {
+				return &ColonNode{}
+			},
+		},
+		{
+			Type:      SEMICOLON,
+			Priority:  PRIORITY_SEMICOLON,
+			Separator: ';',
 			CanMatch: func(s string) bool {
 				return regexp.MustCompile(`(?ms)^[^;\|\s]+(;[^;\|\s]+)+$`).MatchString(s)
 			},
@@ -27,33 +54,52 @@ func init() {
 				return &SemicolonNode{}
 			},
 		},
-		COMMA: {
-			t: COMMA,
+		{
+			Type:      COMMA,
+			Priority:  PRIORITY_COMMA,
+			Separator: ',',
 			CanMatch: func(s string) bool {
-				return regexp.MustCompile(`(?ms)^[-_\.\w]+(,[-_\.\w]+)+$`).MatchString(s)
+				return regexp.MustCompile(`(?ms)^[^,;\|\s]+(,[^,;\|\s]+)+$`).MatchString(s)
 			},
 			NewNode: func() Node {
 				return &CommaNode{}
 			},
 		},
-		FULLSTOP: {
-			t: FULLSTOP,
+		{
+			Type:     BRACKETS,
+			Priority: PRIORITY_BRACKETS,
+			CanMatch: func(s string) bool {
+				return regexp.MustCompile(`(?ms)^(?P<KEY>[-_\w]+\.[-_\w]+)\((?P<VALUE>[^\(\)\s]+)\)$`).MatchString(s)
+			},
+			NewNode: func() Node {
+				return &BracketsNode{}
+			},
+		},
+		{
+			Type:      FULLSTOP,
+			Priority:  PRIORITY_FULLSTOP,
+			Separator: '.',
 			CanMatch: func(s string) bool {
-				return regexp.MustCompile(`(?ms)^(?P<KEY>[-_\w]+)\.(?P<VALUE>[-_\w]+)$`).MatchString(s)
+				return regexp.MustCompile(`(?ms)^(?P<KEY>[-_\w]+)\.(?P<VALUE>[-_\w]+)(` + annotationExpression + `)*$`).MatchString(s)
 			},
 			NewNode: func() Node {
 				return &FullstopNode{}
 			},
 		},
-		COLON: {
-			t: COLON,
+		{
+			Type:     PLACEHOLDER,
+			Priority: PRIORITY_PLACEHOLDER,
 			CanMatch: func(s string) bool {
-				return regexp.MustCompile(`(?ms)^(?P<KEY>[^:\s]+):(?P<VALUE>[^:\s]+)$`).MatchString(s)
+				return regexp.MustCompile(`(?ms)^PH(` + annotationExpression + `)*$`).MatchString(s)
 			},
 			NewNode: func() Node {
-				return &ColonNode{}
+				return &FullstopNode{}
 			},
 		},
+	} {
+		if err := RegisterMatcher(m); err != nil {
+			panic(err)
+		}
 	}
 }
 
@@ -61,18 +107,19 @@ func init() {
 // 	regexp.MustCompile(`(?ms)^format\((?P<VALUE>.*)\)$`).MatchString()
 // }
 
-func GetNodeMatcher(t MarkerType) *Matcher {
-	return nodeMatcherMap[t]
-}
-
 func HasDecoration(c string) bool {
 	return regexp.MustCompile(`(?ms)^[^\|\s]+(\|[^\|\s]+)+$`).MatchString(c)
 }
 
-func ParseDecoration(c string) *PerpendicularNode {
+// ParseDecoration 解析 A.t(1):B.c|A.t(2):C.d 形式的分类，错误同时输出到 Reporter
+func ParseDecoration(c string) (*PerpendicularNode, error) {
 	decorationNode := &PerpendicularNode{}
-	decorationNode.ParseFormation(c)
-	return decorationNode
+	if err := decorationNode.ParseFormation(c); err != nil {
+		err = fmt.Errorf("decoration '%v' can not parse: %v", c, err)
+		utility.GetReporter().Errorf("%v", err)
+		return nil, err
+	}
+	return decorationNode, nil
 }
 
 func TrimSpaceInString(content string) (string, error) {
@@ -84,36 +131,57 @@ func TrimSpaceInString(content string) (string, error) {
 	return SpaceRegexp.ReplaceAllString(content, ""), nil
 }
 
+// TraitFormation 取出单元格中 format(...) 的内容，括号按嵌套匹配，单元格中可以同时有 rules(...)
 func TraitFormation(c string) string {
-	formatRegexp := regexp.MustCompile(`(?ms)format\((?P<VALUE>.*)\)`)
-	subMatchSlice := formatRegexp.FindStringSubmatch(c)
-	// fmt.Printf("DEBUG: subMatchSlice = %v\n", subMatchSlice)
-	if len(subMatchSlice) == 0 {
+	start, end := traitCallLocation(c, "format")
+	if start == -1 {
 		return ""
-	} else {
-		for matchIndex, matchName := range formatRegexp.SubexpNames() {
-			if matchIndex == 0 {
-				continue
-			} else if matchName == "VALUE" {
-				return subMatchSlice[matchIndex]
+	}
+	return c[start+len("format(") : end-1]
+}
+
+// traitCallLocation 返回单元格中 name(...) 的起止位置，括号按嵌套匹配，没有时返回 -1, -1
+func traitCallLocation(c, name string) (int, int) {
+	callRegexp := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\(`)
+	for _, location := range callRegexp.FindAllStringIndex(c, -1) {
+		depth := 0
+		for index := location[1] - 1; index < len(c); index++ {
+			switch c[index] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+			default:
+			}
+			if depth == 0 {
+				return location[0], index + 1
 			}
 		}
 	}
-	return ""
+	return -1, -1
 }
 
-func ParseFormation(c string) Node {
-	var configFormationNode Node
-	if nodeMatcherMap[SEMICOLON].CanMatch(c) {
-		configFormationNode = nodeMatcherMap[SEMICOLON].NewNode()
-	} else if nodeMatcherMap[COMMA].CanMatch(c) {
-		configFormationNode = nodeMatcherMap[COMMA].NewNode()
-	} else if nodeMatcherMap[FULLSTOP].CanMatch(c) {
-		configFormationNode = nodeMatcherMap[FULLSTOP].NewNode()
-	} else {
-		fmt.Printf("Errorf: formation '%v' can not match any marker\n", c)
-		return nil
+// ParseFormation 按优先级选择第一个能匹配的节点解析 formation，只在优先级不高于分号的节点中选择：
+// 竖线、冒号只能出现在 NewFormation 解析的分类中，顶层与分类的值都是分号节点或者分号节点的子节点，
+// 任何一个子节点不能解析时返回错误，错误同时输出到 Reporter
+func ParseFormation(c string) (Node, error) {
+	n, err := parseFormation(c)
+	if err != nil {
+		utility.GetReporter().Errorf("%v", err)
+		return nil, err
+	}
+	return n, nil
+}
+
+// parseFormation 与 ParseFormation 相同但不输出到 Reporter，供分类中的节点使用，错误只在最外层输出一次
+func parseFormation(c string) (Node, error) {
+	m := MatchNode(c, nodeMatcherMap[SEMICOLON].Priority+1)
+	if m == nil {
+		return nil, fmt.Errorf("formation '%v' can not match any marker", c)
+	}
+	configFormationNode := m.NewNode()
+	if err := configFormationNode.ParseFormation(c); err != nil {
+		return nil, fmt.Errorf("formation '%v' can not parse: %v", c, err)
 	}
-	configFormationNode.ParseFormation(c)
-	return configFormationNode
+	return configFormationNode, nil
 }
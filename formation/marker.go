@@ -10,4 +10,15 @@ const (
 	SEMICOLON
 	PERPENDICULAR
 	FORMATE
+	PLACEHOLDER
 )
+
+// 自定义节点的 MarkerType 从这里开始分配
+var nextMarkerType = PLACEHOLDER + 1
+
+// NewMarkerType 为自定义节点分配一个不与内置节点冲突的 MarkerType
+func NewMarkerType() MarkerType {
+	t := nextMarkerType
+	nextMarkerType++
+	return t
+}
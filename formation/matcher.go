@@ -1,7 +1,67 @@
 package formation
 
+import (
+	"fmt"
+	"sort"
+)
+
+// Matcher 匹配 formation 片段并创建对应的节点。
+// ParseFormation 按 Priority 从高到低选择第一个能匹配的节点，列表节点（分号、逗号）只在比自身优先级低的节点中选择子节点，
+// 顶层只选择优先级不高于 PRIORITY_SEMICOLON 的节点，自定义节点的优先级高于分号时只能由自定义的父节点使用；
+// Separator 为节点使用的分隔符，分号与逗号节点按它拆分与拼接内容，没有分隔符的节点为 0
 type Matcher struct {
-	t        MarkerType
-	CanMatch func(string) bool
-	NewNode  func() Node
+	Type      MarkerType
+	Priority  int
+	Separator rune
+	CanMatch  func(string) bool
+	NewNode   func() Node
+}
+
+// 按优先级从高到低排列的 Matcher
+var nodeMatcherSlice []*Matcher
+
+// RegisterMatcher 注册节点，已经注册的 Type 会返回错误，自定义节点的 Type 由 NewMarkerType 分配
+func RegisterMatcher(m *Matcher) error {
+	if m == nil || m.CanMatch == nil || m.NewNode == nil {
+		return fmt.Errorf("matcher CanMatch and NewNode must not be nil")
+	}
+	if _, hasType := nodeMatcherMap[m.Type]; hasType {
+		return fmt.Errorf("matcher type %v already registered", m.Type)
+	}
+	nodeMatcherMap[m.Type] = m
+	if m.Separator != 0 {
+		markerRuneMap[m.Type] = m.Separator
+	}
+	nodeMatcherSlice = append(nodeMatcherSlice, m)
+	sort.SliceStable(nodeMatcherSlice, func(i, j int) bool {
+		if nodeMatcherSlice[i].Priority != nodeMatcherSlice[j].Priority {
+			return nodeMatcherSlice[i].Priority > nodeMatcherSlice[j].Priority
+		}
+		return nodeMatcherSlice[i].Type < nodeMatcherSlice[j].Type
+	})
+	return nil
+}
+
+// separatorOf 返回 Type 节点的 Matcher 声明的分隔符，列表节点按它拆分与拼接内容
+func separatorOf(t MarkerType) string {
+	return string(nodeMatcherMap[t].Separator)
+}
+
+func GetNodeMatcher(t MarkerType) *Matcher {
+	return nodeMatcherMap[t]
+}
+
+// GetNodeMatcherSlice 返回按优先级从高到低排列的所有 Matcher
+func GetNodeMatcherSlice() []*Matcher {
+	return append([]*Matcher(nil), nodeMatcherSlice...)
+}
+
+// MatchNode 返回优先级低于 belowPriority 且能匹配 c 的优先级最高的 Matcher，没有时返回 nil
+func MatchNode(c string, belowPriority int) *Matcher {
+	for _, m := range nodeMatcherSlice {
+		if m.Priority < belowPriority && m.CanMatch(c) {
+			return m
+		}
+	}
+	return nil
 }
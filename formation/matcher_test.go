@@ -0,0 +1,127 @@
+package formation
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+// testTextNode @key 形式的自定义节点，引用 Text 表的字段
+type testTextNode struct {
+	FullstopNode
+}
+
+func (n *testTextNode) ParseFormation(c string) error {
+	n.Formation = c
+	n.Key = "Text"
+	n.Value = c[1:]
+	return nil
+}
+
+func TestRegisterMatcher(t *testing.T) {
+	textType := NewMarkerType()
+	if err := RegisterMatcher(&Matcher{
+		Type:     textType,
+		Priority: PRIORITY_FULLSTOP + 1,
+		CanMatch: regexp.MustCompile(`^@\w+$`).MatchString,
+		NewNode: func() Node {
+			return &testTextNode{}
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// 优先级高于分号的自定义节点不能出现在顶层
+	highType := NewMarkerType()
+	if err := RegisterMatcher(&Matcher{
+		Type:     highType,
+		Priority: PRIORITY_PERPENDICULAR + 1,
+		CanMatch: regexp.MustCompile(`^!\w+$`).MatchString,
+		NewNode: func() Node {
+			return &testTextNode{}
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if GetNodeMatcher(textType) == nil || GetNodeMatcherSlice()[0].Type != highType {
+		t.Errorf("registered matcher is not in the registry ordered by priority")
+	}
+	if err := RegisterMatcher(&Matcher{Type: textType, CanMatch: func(string) bool { return false }, NewNode: func() Node { return nil }}); err == nil {
+		t.Errorf("RegisterMatcher with registered type expect error")
+	}
+	if err := RegisterMatcher(&Matcher{Type: NewMarkerType()}); err == nil {
+		t.Errorf("RegisterMatcher without CanMatch and NewNode expect error")
+	}
+
+	f := testNewFormation(t, "format(A.b,@name;A.b,@name)")
+	if expect := map[string]string{"A": "b", "Text": "name"}; !reflect.DeepEqual(f.GetRelateFileFieldMap(), expect) {
+		t.Errorf("custom node relate map = %v, expect %v", f.GetRelateFileFieldMap(), expect)
+	}
+	if n, err := ParseFormation("@name"); err != nil {
+		t.Errorf("ParseFormation(@name) error = %v", err)
+	} else if _, isTextNode := n.(*testTextNode); !isTextNode {
+		t.Errorf("ParseFormation(@name) expect custom node at top level")
+	}
+	if n, err := ParseFormation("!name"); n != nil || err == nil {
+		t.Errorf("ParseFormation(!name) = %T, %v, expect error for matcher above semicolon", n, err)
+	}
+}
+
+func TestParseFormationTopLevel(t *testing.T) {
+	for _, c := range []struct {
+		formation string
+		expect    Node
+	}{
+		{"A.b", &FullstopNode{}},
+		{"A.b,PH", &CommaNode{}},
+		{"A.b,PH;A.b,PH", &SemicolonNode{}},
+		{"A.t(1)", &BracketsNode{}},
+		{"PH", &FullstopNode{}},
+		// 竖线与冒号只能出现在分类中
+		{"A.t(1):B.c|A.t(2):C.d", nil},
+		{"A.t(1):B.c", nil},
+		{"A", nil},
+	} {
+		n, err := ParseFormation(c.formation)
+		if reflect.TypeOf(n) != reflect.TypeOf(c.expect) || (err != nil) != (c.expect == nil) {
+			t.Errorf("ParseFormation(%q) = %T, %v, expect %T", c.formation, n, err, c.expect)
+		}
+	}
+
+	if _, err := NewFormation("A", "x", "format(A.t(1):B.c)"); err == nil {
+		t.Errorf("NewFormation with top level colon expect error")
+	}
+	if f, err := NewFormation("A", "x", "format(A.t(1):B.c|A.t(2):C.d)"); err != nil || !f.HasDecoration {
+		t.Errorf("NewFormation with decoration = %v, %v", f, err)
+	}
+}
+
+func TestMatcherSeparator(t *testing.T) {
+	for _, c := range []struct {
+		t         MarkerType
+		formation string
+		content   string
+	}{
+		{COMMA, "A.b/PH", "1001/2"},
+		{SEMICOLON, "A.b/A.b", "1001/1002"},
+	} {
+		m := GetNodeMatcher(c.t)
+		separator := m.Separator
+		m.Separator = '/'
+		n := m.NewNode()
+		if err := n.ParseFormation(c.formation); err != nil {
+			t.Errorf("%v ParseFormation(%q) with separator '/' error: %v", c.t, c.formation, err)
+		} else if v, err := n.ParseValue(c.content); err != nil || len(v.Items) != 2 {
+			t.Errorf("%v ParseValue(%q) with separator '/' = %v, %v", c.t, c.content, v, err)
+		} else if formatted, err := n.Format(v); err != nil || formatted != c.content {
+			t.Errorf("%v Format with separator '/' = %q, %v, expect %q", c.t, formatted, err, c.content)
+		}
+		if _, err := formatScalar("a/b"); err == nil {
+			t.Errorf("%v formatScalar(a/b) with separator '/' expect error", c.t)
+		}
+		m.Separator = separator
+	}
+	if _, err := formatScalar("a/b"); err != nil {
+		t.Errorf("formatScalar(a/b) error: %v", err)
+	}
+}
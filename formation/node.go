@@ -2,18 +2,22 @@ package formation
 
 import (
 	"fmt"
+	"reflect"
 	"regexp"
 	"strings"
 )
 
 type Node interface {
 	CanMatch(string) bool
-	ParseFormation(string) bool
+	ParseFormation(string) error
 	ParseContent(string) (map[string]map[string][]string, []error)
+	ParseValue(string) (*Value, error)
+	Format(*Value) (string, error)
 	GetKey() string
 	GetValue() string
 	GetFormation() string
 	GetRelateFileFieldMap() map[string]string
+	Children() []Node
 }
 
 type BaseNode struct {
@@ -39,30 +43,33 @@ func (n *SemicolonNode) CanMatch(c string) bool {
 	return nodeMatcherMap[SEMICOLON].CanMatch(c)
 }
 
-func (n *SemicolonNode) ParseFormation(c string) bool {
+func (n *SemicolonNode) ParseFormation(c string) error {
 	n.Formation = c
 	if len(n.Formation) == 0 {
-		fmt.Printf("Error: semicolon node formation is empty\n")
-		return false
+		return fmt.Errorf("semicolon node formation is empty")
 	}
 
-	for _, subFormation := range strings.Split(n.Formation, ";") {
-		if !nodeMatcherMap[COMMA].CanMatch(subFormation) {
-			fmt.Printf("Error: semicolon node can not match sub content '%v'\n", subFormation)
-		} else {
-			subNode := nodeMatcherMap[COMMA].NewNode()
-			subNode.ParseFormation(subFormation)
+	// 每组的格式相同，每组都要能解析，取第一组作为子节点
+	for _, subFormation := range strings.Split(n.Formation, separatorOf(SEMICOLON)) {
+		m := MatchNode(subFormation, nodeMatcherMap[SEMICOLON].Priority)
+		if m == nil {
+			return fmt.Errorf("semicolon node can not match sub content '%v'", subFormation)
+		}
+		subNode := m.NewNode()
+		if err := subNode.ParseFormation(subFormation); err != nil {
+			return err
+		}
+		if n.SubNode == nil {
 			n.SubNode = subNode
-			break
 		}
 	}
-	return true
+	return nil
 }
 
 func (n *SemicolonNode) ParseContent(c string) (map[string]map[string][]string, []error) {
 	parseContentErrorSlice := make([]error, 0)
 	fileFieldContentSliceMap := make(map[string]map[string][]string)
-	for _, subContent := range strings.Split(c, ";") {
+	for _, subContent := range strings.Split(c, separatorOf(SEMICOLON)) {
 		subNodeResultMap, errorSlice := n.SubNode.ParseContent(subContent)
 		parseContentErrorSlice = append(parseContentErrorSlice, errorSlice...)
 		fileFieldContentSliceMap = MergeFileFieldContentSliceMap(fileFieldContentSliceMap, subNodeResultMap)
@@ -70,6 +77,33 @@ func (n *SemicolonNode) ParseContent(c string) (map[string]map[string][]string,
 	return fileFieldContentSliceMap, parseContentErrorSlice
 }
 
+func (n *SemicolonNode) ParseValue(c string) (*Value, error) {
+	v := &Value{Kind: VALUE_LIST}
+	for _, subContent := range strings.Split(c, separatorOf(SEMICOLON)) {
+		subValue, err := n.SubNode.ParseValue(subContent)
+		if err != nil {
+			return nil, err
+		}
+		v.Items = append(v.Items, subValue)
+	}
+	return v, nil
+}
+
+func (n *SemicolonNode) Format(v *Value) (string, error) {
+	if v == nil || v.Kind != VALUE_LIST || len(v.Items) == 0 {
+		return "", fmt.Errorf("semicolon node '%v' requires non-empty list value, got %v", n.Formation, v.Interface())
+	}
+	subContentSlice := make([]string, 0, len(v.Items))
+	for _, item := range v.Items {
+		subContent, err := n.SubNode.Format(item)
+		if err != nil {
+			return "", err
+		}
+		subContentSlice = append(subContentSlice, subContent)
+	}
+	return strings.Join(subContentSlice, separatorOf(SEMICOLON)), nil
+}
+
 func (n *SemicolonNode) GetFormation() string {
 	return n.Formation
 }
@@ -78,91 +112,141 @@ func (n *SemicolonNode) GetRelateFileFieldMap() map[string]string {
 	return n.SubNode.GetRelateFileFieldMap()
 }
 
+func (n *SemicolonNode) Children() []Node {
+	if n.SubNode == nil {
+		return nil
+	}
+	return []Node{n.SubNode}
+}
+
 type CommaNode struct {
 	BaseNode
 	Formation    string
 	SubNodeSlice []Node
+	// CheckSlice 列表中 check(...) 形式的组注解，不占用内容中的位置
+	CheckSlice []*Annotation
 }
 
 func (n *CommaNode) CanMatch(c string) bool {
 	return nodeMatcherMap[COMMA].CanMatch(c)
 }
 
-func (n *CommaNode) ParseFormation(c string) bool {
+func (n *CommaNode) ParseFormation(c string) error {
 	n.Formation = c
 	if len(n.Formation) == 0 {
-		fmt.Printf("Error: comma node formation is empty\n")
-		return false
+		return fmt.Errorf("comma node formation is empty")
 	}
 
 	same := true
 	var lastSubFormation string
-	for _, subFormation := range strings.Split(n.Formation, ",") {
-		if !nodeMatcherMap[FULLSTOP].CanMatch(subFormation) && subFormation != "PH" {
-			fmt.Printf("Error: comma node can not match sub content '%v'\n", subFormation)
-		} else {
-			if len(lastSubFormation) != 0 {
-				if same {
-					same = lastSubFormation == subFormation
-				}
+	for _, subFormation := range strings.Split(n.Formation, separatorOf(COMMA)) {
+		if check := parseCheckAnnotation(subFormation); check != nil {
+			n.CheckSlice = append(n.CheckSlice, check)
+			continue
+		}
+		m := MatchNode(subFormation, nodeMatcherMap[COMMA].Priority)
+		if m == nil {
+			return fmt.Errorf("comma node can not match sub content '%v'", subFormation)
+		}
+		if len(lastSubFormation) != 0 {
+			if same {
+				same = lastSubFormation == subFormation
 			}
-			subNode := nodeMatcherMap[FULLSTOP].NewNode()
-			subNode.ParseFormation(subFormation)
-			n.SubNodeSlice = append(n.SubNodeSlice, subNode)
-			lastSubFormation = subFormation
 		}
+		subNode := m.NewNode()
+		if err := subNode.ParseFormation(subFormation); err != nil {
+			return err
+		}
+		n.SubNodeSlice = append(n.SubNodeSlice, subNode)
+		lastSubFormation = subFormation
 	}
 
+	if len(n.SubNodeSlice) == 0 {
+		return fmt.Errorf("comma node '%v' has no slot", n.Formation)
+	}
 	if same {
 		n.SubNodeSlice = n.SubNodeSlice[:1]
 	}
 
-	return true
+	return nil
 }
 
 func (n *CommaNode) ParseContent(c string) (map[string]map[string][]string, []error) {
 	parseContentErrorSlice := make([]error, 0)
 	fileFieldContentSliceMap := make(map[string]map[string][]string)
-	subContentSlice := strings.Split(c, ",")
+	subContentSlice := strings.Split(c, separatorOf(COMMA))
+	if len(n.SubNodeSlice) != 1 && len(subContentSlice) != len(n.SubNodeSlice) {
+		parseContentErrorSlice = append(parseContentErrorSlice, fmt.Errorf("sub node length %v is not equal 1 or sub content slice %v length %v", len(n.SubNodeSlice), subContentSlice, len(subContentSlice)))
+		return fileFieldContentSliceMap, parseContentErrorSlice
+	}
+	for index, subContent := range subContentSlice {
+		subNode := n.SubNodeSlice[0]
+		if len(n.SubNodeSlice) != 1 {
+			subNode = n.SubNodeSlice[index]
+		}
+		subNodeResultMap, errorSlice := subNode.ParseContent(subContent)
+		parseContentErrorSlice = append(parseContentErrorSlice, errorSlice...)
+		fileFieldContentSliceMap = MergeFileFieldContentSliceMap(fileFieldContentSliceMap, subNodeResultMap)
+	}
+	return fileFieldContentSliceMap, parseContentErrorSlice
+}
+
+// ParseValue 子节点都相同时解析为列表，否则按位置解析为元组
+func (n *CommaNode) ParseValue(c string) (*Value, error) {
+	subContentSlice := strings.Split(c, separatorOf(COMMA))
+	v := &Value{}
 	if len(n.SubNodeSlice) == 1 {
+		v.Kind = VALUE_LIST
 		for _, subContent := range subContentSlice {
-			// fmt.Printf("DEBUG: sub content '%v' formation is '%v'\n", subContent, n.SubNodeSlice[0].GetFormation())
-			if n.SubNodeSlice[0].GetFormation() == "PH" {
-				continue
+			subValue, err := n.SubNodeSlice[0].ParseValue(subContent)
+			if err != nil {
+				return nil, err
 			}
-			if _, hasFile := fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()]; !hasFile {
-				fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()] = make(map[string][]string)
-			}
-			if _, hasField := fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()][n.SubNodeSlice[0].GetValue()]; !hasField {
-				fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()][n.SubNodeSlice[0].GetValue()] = make([]string, 0)
-			}
-			fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()][n.SubNodeSlice[0].GetValue()] = append(fileFieldContentSliceMap[n.SubNodeSlice[0].GetKey()][n.SubNodeSlice[0].GetValue()], subContent)
+			v.Items = append(v.Items, subValue)
 		}
 	} else if len(subContentSlice) == len(n.SubNodeSlice) {
+		v.Kind = VALUE_TUPLE
 		for index, subContent := range subContentSlice {
-			// fmt.Printf("DEBUG: sub content '%v' formation is '%v'\n", subContent, n.SubNodeSlice[index].GetFormation())
-			if n.SubNodeSlice[index].GetFormation() == "PH" {
-				continue
-			}
-			if _, hasFile := fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()]; !hasFile {
-				fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()] = make(map[string][]string)
-			}
-			if _, hasField := fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()][n.SubNodeSlice[index].GetValue()]; !hasField {
-				fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()][n.SubNodeSlice[index].GetValue()] = make([]string, 0)
+			subValue, err := n.SubNodeSlice[index].ParseValue(subContent)
+			if err != nil {
+				return nil, err
 			}
-			fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()][n.SubNodeSlice[index].GetValue()] = append(fileFieldContentSliceMap[n.SubNodeSlice[index].GetKey()][n.SubNodeSlice[index].GetValue()], subContent)
+			v.Items = append(v.Items, subValue)
 		}
+		nameTupleItem(v.Items)
 	} else {
-		// fmt.Printf("Error: sub node %v length %v is not equal 1 or sub content slice %v length %v\n", func() string {
-		// 	s := make([]string, 0)
-		// 	for _, n := range n.SubNodeSlice {
-		// 		s = append(s, fmt.Sprintf("'%v'", n.GetFormation()))
-		// 	}
-		// 	return strings.Join(s, " ")
-		// }(), len(n.SubNodeSlice), subContentSlice, len(subContentSlice))
-		parseContentErrorSlice = append(parseContentErrorSlice, fmt.Errorf("sub node length %v is not equal 1 or sub content slice %v length %v", len(n.SubNodeSlice), subContentSlice, len(subContentSlice)))
+		return nil, fmt.Errorf("sub node length %v is not equal 1 or sub content slice %v length %v", len(n.SubNodeSlice), subContentSlice, len(subContentSlice))
 	}
-	return fileFieldContentSliceMap, parseContentErrorSlice
+	v.AnnotationSlice = n.CheckSlice
+	return v, nil
+}
+
+// Format 子节点都相同时要求列表，否则要求与子节点数量相同的元组
+func (n *CommaNode) Format(v *Value) (string, error) {
+	if v == nil {
+		return "", fmt.Errorf("comma node '%v' value is nil", n.Formation)
+	}
+	if len(n.SubNodeSlice) == 1 {
+		if v.Kind != VALUE_LIST || len(v.Items) == 0 {
+			return "", fmt.Errorf("comma node '%v' requires non-empty list value, got %v", n.Formation, v.Interface())
+		}
+	} else if v.Kind != VALUE_TUPLE || len(v.Items) != len(n.SubNodeSlice) {
+		return "", fmt.Errorf("comma node '%v' requires tuple value of length %v, got %v", n.Formation, len(n.SubNodeSlice), v.Interface())
+	}
+
+	subContentSlice := make([]string, 0, len(v.Items))
+	for index, item := range v.Items {
+		subNode := n.SubNodeSlice[0]
+		if len(n.SubNodeSlice) != 1 {
+			subNode = n.SubNodeSlice[index]
+		}
+		subContent, err := subNode.Format(item)
+		if err != nil {
+			return "", err
+		}
+		subContentSlice = append(subContentSlice, subContent)
+	}
+	return strings.Join(subContentSlice, separatorOf(COMMA)), nil
 }
 
 func (n *CommaNode) GetFormation() string {
@@ -179,36 +263,68 @@ func (n *CommaNode) GetRelateFileFieldMap() map[string]string {
 	return relateFileFiledMap
 }
 
+func (n *CommaNode) Children() []Node {
+	return n.SubNodeSlice
+}
+
 type FullstopNode struct {
 	BaseNode
 	Formation     string
 	IsPlaceHolder bool
+	// AnnotationSlice A.b:name(arg) 形式的槽位注解
+	AnnotationSlice []*Annotation
 }
 
 func (n *FullstopNode) CanMatch(c string) bool {
 	return nodeMatcherMap[FULLSTOP].CanMatch(c)
 }
 
-func (n *FullstopNode) ParseFormation(c string) bool {
+func (n *FullstopNode) ParseFormation(c string) error {
 	n.Formation = c
-	if c == "PH" {
+	slot, annotationSlice := splitSlotAnnotation(c)
+	if len(annotationSlice) != 0 {
+		n.AnnotationSlice = annotationSlice
+	}
+	if slot == "PH" {
 		n.IsPlaceHolder = true
 	} else {
-		fullstopIndex := strings.IndexRune(c, markerRuneMap[FULLSTOP])
-		n.Key = c[:fullstopIndex]
-		n.Value = c[fullstopIndex+1:]
+		fullstopIndex := strings.IndexRune(slot, markerRuneMap[FULLSTOP])
+		if fullstopIndex == -1 {
+			return fmt.Errorf("fullstop node '%v' does not contain '%c'", c, markerRuneMap[FULLSTOP])
+		}
+		n.Key = slot[:fullstopIndex]
+		n.Value = slot[fullstopIndex+1:]
 	}
-	return true
+	return nil
 }
 
 func (n *FullstopNode) ParseContent(c string) (map[string]map[string][]string, []error) {
 	// fmt.Printf("DEBUG: content '%v' formation is '%v.%v'\n", c, n.Key, n.Value)
 	fileFieldContentSliceMap := make(map[string]map[string][]string)
+	if n.IsPlaceHolder {
+		return fileFieldContentSliceMap, nil
+	}
 	fileFieldContentSliceMap[n.Key] = make(map[string][]string)
 	fileFieldContentSliceMap[n.Key][n.Value] = append(fileFieldContentSliceMap[n.Key][n.Value], c)
 	return fileFieldContentSliceMap, nil
 }
 
+func (n *FullstopNode) ParseValue(c string) (*Value, error) {
+	v := &Value{Kind: VALUE_SCALAR, Scalar: parseScalar(c), AnnotationSlice: n.AnnotationSlice}
+	if !n.IsPlaceHolder {
+		v.Name = n.Value
+		v.Ref = n.Key + string(markerRuneMap[FULLSTOP]) + n.Value
+	}
+	return v, nil
+}
+
+func (n *FullstopNode) Format(v *Value) (string, error) {
+	if v == nil || v.Kind != VALUE_SCALAR {
+		return "", fmt.Errorf("'%v' requires scalar value, got %v", n.Formation, v.Interface())
+	}
+	return formatScalar(v.Scalar)
+}
+
 func (n *FullstopNode) GetFormation() string {
 	return n.Formation
 }
@@ -220,6 +336,10 @@ func (n *FullstopNode) GetRelateFileFieldMap() map[string]string {
 	return map[string]string{n.Key: n.Value}
 }
 
+func (n *FullstopNode) Children() []Node {
+	return nil
+}
+
 type ColonNode struct {
 	BaseNode
 	Formation string
@@ -231,24 +351,29 @@ func (n *ColonNode) CanMatch(c string) bool {
 	return nodeMatcherMap[COLON].CanMatch(c)
 }
 
-func (n *ColonNode) ParseFormation(c string) bool {
+func (n *ColonNode) ParseFormation(c string) error {
 	n.Formation = c
 
 	index := strings.IndexRune(c, markerRuneMap[COLON])
 	if index == -1 {
-		return false
+		return fmt.Errorf("colon node '%v' does not contain '%c'", c, markerRuneMap[COLON])
 	}
 
-	if !regexp.MustCompile(`(?ms)^(?P<KEY>[^\(\)]+)\((?P<VALUE>[^\(\)]+)\)$`).MatchString(c[:index]) {
-		fmt.Printf("Error: colon key '%v' does not match brackets marker\n", c[:index])
-		return false
+	if !nodeMatcherMap[BRACKETS].CanMatch(c[:index]) {
+		return fmt.Errorf("colon key '%v' does not match brackets marker", c[:index])
 	}
 
 	n.KeyNode = &BracketsNode{}
-	n.KeyNode.ParseFormation(c[:index])
-	n.ValueNode = ParseFormation(c[index+1:])
+	if err := n.KeyNode.ParseFormation(c[:index]); err != nil {
+		return err
+	}
+	valueNode, err := parseFormation(c[index+1:])
+	if err != nil {
+		return err
+	}
+	n.ValueNode = valueNode
 
-	return true
+	return nil
 }
 
 func (n *ColonNode) ParseContent(c string) (map[string]map[string][]string, []error) {
@@ -256,6 +381,14 @@ func (n *ColonNode) ParseContent(c string) (map[string]map[string][]string, []er
 	return n.ValueNode.ParseContent(c)
 }
 
+func (n *ColonNode) ParseValue(c string) (*Value, error) {
+	return n.ValueNode.ParseValue(c)
+}
+
+func (n *ColonNode) Format(v *Value) (string, error) {
+	return n.ValueNode.Format(v)
+}
+
 func (n *ColonNode) GetFormation() string {
 	return n.Formation
 }
@@ -283,27 +416,44 @@ func (n *ColonNode) GetRelateFileFieldMap() map[string]string {
 	return relateFileFieldMap
 }
 
+func (n *ColonNode) Children() []Node {
+	childSlice := make([]Node, 0, 2)
+	if n.KeyNode != nil {
+		childSlice = append(childSlice, n.KeyNode)
+	}
+	if n.ValueNode != nil {
+		childSlice = append(childSlice, n.ValueNode)
+	}
+	return childSlice
+}
+
+// PerpendicularNode 按分类字段的值选择分支，Key 与 Value 为分类字段的 File 与 field
 type PerpendicularNode struct {
+	BaseNode
 	Formation               string
 	RefKeyFormationNode     Node
 	RefValueSubFormationMap map[string]*ColonNode
 }
 
-func (n *PerpendicularNode) ParseFormation(c string) bool {
+func (n *PerpendicularNode) CanMatch(c string) bool {
+	return nodeMatcherMap[PERPENDICULAR].CanMatch(c)
+}
+
+func (n *PerpendicularNode) ParseFormation(c string) error {
 	n.Formation = c
 	n.RefValueSubFormationMap = make(map[string]*ColonNode)
 	subContentSlice := strings.Split(c, "|")
 	var relateFormation string
 	for _, subContent := range subContentSlice {
 		if !nodeMatcherMap[COLON].CanMatch(subContent) {
-			fmt.Printf("Error: perpendicular sub-content '%v' does not match colon matcher\n", subContent)
-			return false
+			return fmt.Errorf("perpendicular sub-content '%v' does not match colon matcher", subContent)
 		}
 		subNode := &ColonNode{}
-		subNode.ParseFormation(subContent)
+		if err := subNode.ParseFormation(subContent); err != nil {
+			return err
+		}
 		if _, hasKey := n.RefValueSubFormationMap[subNode.GetKeyRelateValue()]; hasKey {
-			fmt.Printf("Error: in RefValueSubFormationMap, key '%v' already exists in decoration '%v'\n", subNode.GetKeyRelateValue(), n.Formation)
-			return false
+			return fmt.Errorf("in RefValueSubFormationMap, key '%v' already exists in decoration '%v'", subNode.GetKeyRelateValue(), n.Formation)
 		}
 		// 暂时以引用的 value 作为 key，意味着一个字段只能引用一个字段的值作为分类的键
 		// fmt.Printf("DEBUG: subNode.GetKeyRelateValue() = %v, subNode = %+v\n", subNode.GetKeyRelateValue(), subNode)
@@ -311,18 +461,110 @@ func (n *PerpendicularNode) ParseFormation(c string) bool {
 		n.RefValueSubFormationMap[subNode.GetKeyRelateValue()] = subNode
 		if len(relateFormation) == 0 {
 			n.RefKeyFormationNode = subNode.GetKeyRelateFormationNode()
-		} else if relateFormation != n.RefKeyFormationNode.GetFormation() {
-			fmt.Printf("Error: in RefValueSubFormationMap, key '%v' relate formation '%v' is not same as relate formation '%v'\n", subNode.GetKeyRelateValue(), n.RefKeyFormationNode.GetFormation(), relateFormation)
-			return false
+			n.Key = n.RefKeyFormationNode.GetKey()
+			n.Value = n.RefKeyFormationNode.GetValue()
+			relateFormation = n.RefKeyFormationNode.GetFormation()
+		} else if keyRelateFormation := subNode.GetKeyRelateFormationNode().GetFormation(); relateFormation != keyRelateFormation {
+			return fmt.Errorf("in RefValueSubFormationMap, key '%v' relate formation '%v' is not same as relate formation '%v'", subNode.GetKeyRelateValue(), keyRelateFormation, relateFormation)
 		}
 	}
-	return true
+	return nil
 }
 
 func (n *PerpendicularNode) GetFormation() string {
 	return n.Formation
 }
 
+// ParseContentByKey 按分类字段的值选择分支解析单元格
+func (n *PerpendicularNode) ParseContentByKey(refValue, c string) (map[string]map[string][]string, []error) {
+	refNode, hasRefNode := n.RefValueSubFormationMap[refValue]
+	if !hasRefNode {
+		return nil, []error{fmt.Errorf("decoration '%v' has no branch for reference value %v", n.Formation, refValue)}
+	}
+	return refNode.ParseContent(c)
+}
+
+// ParseContent 不知道分类字段的值时，只有所有能解析该单元格的分支结果都相同才能确定结果，否则需要使用 ParseContentByKey
+func (n *PerpendicularNode) ParseContent(c string) (map[string]map[string][]string, []error) {
+	var result map[string]map[string][]string
+	var resultRefValue string
+	for _, refValue := range sortRefValueSlice(n.RefValueSubFormationMap) {
+		r, errorSlice := n.RefValueSubFormationMap[refValue].ParseContent(c)
+		if len(errorSlice) != 0 {
+			continue
+		}
+		if len(resultRefValue) == 0 {
+			result, resultRefValue = r, refValue
+		} else if !reflect.DeepEqual(r, result) {
+			return nil, []error{fmt.Errorf("decoration '%v' branch %v and %v parse content '%v' differently, use ParseContentByKey", n.Formation, resultRefValue, refValue, c)}
+		}
+	}
+	if len(resultRefValue) == 0 {
+		return nil, []error{fmt.Errorf("decoration '%v' has no branch can parse content '%v'", n.Formation, c)}
+	}
+	return result, nil
+}
+
+// ParseValue 同 ParseContent，分支结果不同时需要使用 ParseValueByKey
+func (n *PerpendicularNode) ParseValue(c string) (*Value, error) {
+	var result *Value
+	var resultRefValue string
+	for _, refValue := range sortRefValueSlice(n.RefValueSubFormationMap) {
+		v, err := n.RefValueSubFormationMap[refValue].ParseValue(c)
+		if err != nil {
+			continue
+		}
+		if len(resultRefValue) == 0 {
+			result, resultRefValue = v, refValue
+		} else if !reflect.DeepEqual(v, result) {
+			return nil, fmt.Errorf("decoration '%v' branch %v and %v parse content '%v' differently, use ParseValueByKey", n.Formation, resultRefValue, refValue, c)
+		}
+	}
+	if len(resultRefValue) == 0 {
+		return nil, fmt.Errorf("decoration '%v' has no branch can parse content '%v'", n.Formation, c)
+	}
+	return result, nil
+}
+
+// ParseValueByKey 按分类字段的值选择分支解析单元格
+func (n *PerpendicularNode) ParseValueByKey(refValue, c string) (*Value, error) {
+	refNode, hasRefNode := n.RefValueSubFormationMap[refValue]
+	if !hasRefNode {
+		return nil, fmt.Errorf("decoration '%v' has no branch for reference value %v", n.Formation, refValue)
+	}
+	return refNode.ParseValue(c)
+}
+
+// FormatByKey 按分类字段的值选择分支输出单元格
+func (n *PerpendicularNode) FormatByKey(refValue string, v *Value) (string, error) {
+	refNode, hasRefNode := n.RefValueSubFormationMap[refValue]
+	if !hasRefNode {
+		return "", fmt.Errorf("decoration '%v' has no branch for reference value %v", n.Formation, refValue)
+	}
+	return refNode.Format(v)
+}
+
+// Format 值不带分类字段的值，只有所有能输出该值的分支结果都相同时才能确定输出，否则需要使用 FormatByKey
+func (n *PerpendicularNode) Format(v *Value) (string, error) {
+	var content string
+	var contentRefValue string
+	for _, refValue := range sortRefValueSlice(n.RefValueSubFormationMap) {
+		c, err := n.RefValueSubFormationMap[refValue].Format(v)
+		if err != nil {
+			continue
+		}
+		if len(contentRefValue) == 0 {
+			content, contentRefValue = c, refValue
+		} else if c != content {
+			return "", fmt.Errorf("decoration '%v' branch %v and %v format value differently, use FormatByKey", n.Formation, contentRefValue, refValue)
+		}
+	}
+	if len(contentRefValue) == 0 {
+		return "", fmt.Errorf("decoration '%v' has no branch can format %v", n.Formation, v.Interface())
+	}
+	return content, nil
+}
+
 func (n *PerpendicularNode) GetFormationNodeByKey(key string) Node {
 	return nil
 }
@@ -337,29 +579,77 @@ func (n *PerpendicularNode) GetRelateFileFieldMap() map[string]string {
 	return relateFileFieldMap
 }
 
+// Children 按分支的键排序
+func (n *PerpendicularNode) Children() []Node {
+	childSlice := make([]Node, 0, len(n.RefValueSubFormationMap))
+	for _, refValue := range sortRefValueSlice(n.RefValueSubFormationMap) {
+		childSlice = append(childSlice, n.RefValueSubFormationMap[refValue])
+	}
+	return childSlice
+}
+
+// BracketsNode File.field(value) 形式的分类条件，Key 为引用的字段，解析与输出内容时按该字段处理
 type BracketsNode struct {
 	Formation string
 	Key       Node
 	Value     string
 }
 
-func (n *BracketsNode) ParseFormation(c string) {
+func (n *BracketsNode) CanMatch(c string) bool {
+	return nodeMatcherMap[BRACKETS].CanMatch(c)
+}
+
+func (n *BracketsNode) ParseFormation(c string) error {
 	n.Formation = c
 	bracketsRegexp := regexp.MustCompile(`(?ms)^(?P<KEY>[^\(\)]+)\((?P<VALUE>[^\(\)]+)\)$`)
 
 	subMatchSlice := bracketsRegexp.FindStringSubmatch(c)
+	if len(subMatchSlice) == 0 {
+		return fmt.Errorf("brackets formation '%v' does not match brackets marker", c)
+	}
 	for subMatchIndex, subMatchName := range bracketsRegexp.SubexpNames() {
 		if subMatchName == "KEY" {
 			if !nodeMatcherMap[FULLSTOP].CanMatch(subMatchSlice[subMatchIndex]) {
-				fmt.Printf("Error: bracket key '%v' does not match fullstop marker\n", subMatchSlice[subMatchIndex])
-				return
+				return fmt.Errorf("bracket key '%v' does not match fullstop marker", subMatchSlice[subMatchIndex])
 			}
 			n.Key = nodeMatcherMap[FULLSTOP].NewNode()
-			n.Key.ParseFormation(subMatchSlice[subMatchIndex])
+			if err := n.Key.ParseFormation(subMatchSlice[subMatchIndex]); err != nil {
+				return err
+			}
 		} else if subMatchName == "VALUE" {
 			n.Value = subMatchSlice[subMatchIndex]
 		}
 	}
+	return nil
+}
+
+func (n *BracketsNode) ParseContent(c string) (map[string]map[string][]string, []error) {
+	return n.Key.ParseContent(c)
+}
+
+func (n *BracketsNode) ParseValue(c string) (*Value, error) {
+	return n.Key.ParseValue(c)
+}
+
+func (n *BracketsNode) Format(v *Value) (string, error) {
+	return n.Key.Format(v)
+}
+
+// GetKey 返回引用的 File.field
+func (n *BracketsNode) GetKey() string {
+	if n.Key == nil {
+		return ""
+	}
+	return n.Key.GetFormation()
+}
+
+// GetValue 返回括号中的值
+func (n *BracketsNode) GetValue() string {
+	return n.Value
+}
+
+func (n *BracketsNode) GetFormation() string {
+	return n.Formation
 }
 
 func (n *BracketsNode) GetRelateFormation() string {
@@ -378,6 +668,13 @@ func (n *BracketsNode) GetRelateFileFieldMap() map[string]string {
 	return relateFileFieldMap
 }
 
+func (n *BracketsNode) Children() []Node {
+	if n.Key == nil {
+		return nil
+	}
+	return []Node{n.Key}
+}
+
 func MergeFileFieldContentSliceMap(o, n map[string]map[string][]string) map[string]map[string][]string {
 	for filename, fieldContentSliceMap := range n {
 		if _, hasFile := o[filename]; !hasFile {
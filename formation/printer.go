@@ -0,0 +1,67 @@
+package formation
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// PrintFormation 输出规范形式的 format(...)：去掉所有空白，
+// 有分类时分支按引用的值排序，每个分支一行并以 | 结尾，与 main.go 示例的写法相同
+func PrintFormation(f *Formation) string {
+	nullable := ""
+	if f.Nullable {
+		nullable = "?"
+	}
+	if !f.HasDecoration {
+		return fmt.Sprintf("format(%v%v)", f.FormationNode.GetFormation(), nullable)
+	}
+
+	builder := &strings.Builder{}
+	builder.WriteString("format(\n")
+	refValueSlice := sortRefValueSlice(f.DecorationNode.RefValueSubFormationMap)
+	for index, refValue := range refValueSlice {
+		builder.WriteString("\t")
+		builder.WriteString(f.DecorationNode.RefValueSubFormationMap[refValue].GetFormation())
+		if index != len(refValueSlice)-1 {
+			builder.WriteString("|")
+		} else {
+			builder.WriteString(nullable)
+		}
+		builder.WriteString("\n")
+	}
+	builder.WriteString(")")
+	return builder.String()
+}
+
+// sortRefValueSlice 分支的键都是整数时按数值排序，否则按字符串排序
+func sortRefValueSlice(refValueSubFormationMap map[string]*ColonNode) []string {
+	refValueSlice := make([]string, 0, len(refValueSubFormationMap))
+	for refValue := range refValueSubFormationMap {
+		refValueSlice = append(refValueSlice, refValue)
+	}
+	sort.Slice(refValueSlice, func(i, j int) bool {
+		iValue, iErr := strconv.ParseInt(refValueSlice[i], 10, 64)
+		jValue, jErr := strconv.ParseInt(refValueSlice[j], 10, 64)
+		if iErr == nil && jErr == nil && iValue != jValue {
+			return iValue < jValue
+		}
+		return refValueSlice[i] < refValueSlice[j]
+	})
+	return refValueSlice
+}
+
+// FormatCell 将 formation 行的单元格中的 format(...) 替换为规范形式，format 之外的内容保持不变，
+// 没有 format 的单元格原样返回
+func FormatCell(file, field, content string) (string, error) {
+	f, err := NewFormation(file, field, content)
+	if err != nil {
+		return "", err
+	}
+	if f == nil {
+		return content, nil
+	}
+	start, end := traitCallLocation(content, "format")
+	return content[:start] + PrintFormation(f) + content[end:], nil
+}
@@ -0,0 +1,472 @@
+package formation
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-formation/utility"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 列规则，写在 formation 行中 format(...) 的旁边，如 rules(unique,monotonic,non-empty,sum-per-group(by=pool_id)=10000)，
+// 每张表对整列检查一次，补充单元格内无法表达的约束
+const (
+	// RULE_UNIQUE 列中的值不重复，空值不参与
+	RULE_UNIQUE = "unique"
+	// RULE_MONOTONIC 按行的顺序不递减，monotonic(strict) 表示严格递增，空值不参与
+	RULE_MONOTONIC = "monotonic"
+	// RULE_NON_EMPTY 每一行都不能为空
+	RULE_NON_EMPTY = "non-empty"
+	// RULE_SUM_PER_GROUP sum-per-group(by=pool_id)=10000 按 pool_id 分组后每组之和等于 10000，省略 by 时对整列求和
+	RULE_SUM_PER_GROUP = "sum-per-group"
+)
+
+// 规则 name、name(param,key=value) 或 name(key=value)=arg，参数中不能出现括号
+var ruleRegexp = regexp.MustCompile(`^(?P<NAME>[-\w]+)(\((?P<PARAM>[^\(\)]*)\))?(=(?P<ARG>[^\(\)=,]+))?$`)
+
+// Rule rules(...) 中的一条规则
+type Rule struct {
+	Formation string
+	Name      string
+	// ParamMap 括号中的参数，只有键没有值的参数值为空字符串
+	ParamMap map[string]string
+	// Arg 等号之后的值
+	Arg string
+}
+
+func (r *Rule) String() string {
+	return r.Formation
+}
+
+// Rules formation 行单元格中 rules(...) 声明的列规则
+type Rules struct {
+	File      string
+	Field     string
+	RuleSlice []*Rule
+}
+
+// columnRule 列规则的实现，CheckParam 在解析时校验参数，NewCheck 为 format 中的 field 列创建逐行检查
+type columnRule struct {
+	CheckParam func(r *Rule) error
+	NewCheck   func(r *Rule, format map[string]int, field string) (*columnCheck, error)
+}
+
+// columnCheck 按行的顺序接收每一行的数据，Close 返回整列的检查结果，不需要在内存中保留整张表
+type columnCheck struct {
+	CheckRow func(row int, rowDataSlice []interface{})
+	Close    func() []error
+}
+
+var columnRuleMap map[string]*columnRule
+
+func init() {
+	columnRuleMap = map[string]*columnRule{
+		RULE_UNIQUE: {
+			CheckParam: noRuleParam,
+			NewCheck: func(r *Rule, format map[string]int, field string) (*columnCheck, error) {
+				valueSlice := make([]string, 0)
+				valueRowSliceMap := make(map[string][]int)
+				return &columnCheck{
+					CheckRow: columnDataHandle(format[field], func(row int, data interface{}) {
+						c := fmt.Sprintf("%v", data)
+						if _, hasValue := valueRowSliceMap[c]; !hasValue {
+							valueSlice = append(valueSlice, c)
+						}
+						valueRowSliceMap[c] = append(valueRowSliceMap[c], row)
+					}),
+					Close: func() []error {
+						checkErrorSlice := make([]error, 0)
+						for _, c := range valueSlice {
+							if len(valueRowSliceMap[c]) > 1 {
+								checkErrorSlice = append(checkErrorSlice, fmt.Errorf("value %v is duplicated in rows %v", c, valueRowSliceMap[c]))
+							}
+						}
+						return checkErrorSlice
+					},
+				}, nil
+			},
+		},
+		RULE_MONOTONIC: {
+			CheckParam: func(r *Rule) error {
+				for key := range r.ParamMap {
+					if key != "strict" {
+						return fmt.Errorf("unknown param '%v'", key)
+					}
+				}
+				return checkNoRuleArg(r)
+			},
+			NewCheck: func(r *Rule, format map[string]int, field string) (*columnCheck, error) {
+				_, isStrict := r.ParamMap["strict"]
+				checkErrorSlice := make([]error, 0)
+				lastRow, last := -1, 0.0
+				return &columnCheck{
+					CheckRow: columnDataHandle(format[field], func(row int, data interface{}) {
+						current, err := dataToFloat(data)
+						if err != nil {
+							checkErrorSlice = append(checkErrorSlice, fmt.Errorf("row %v: %v", row, err))
+							return
+						}
+						if lastRow != -1 && (current < last || (isStrict && current == last)) {
+							checkErrorSlice = append(checkErrorSlice, fmt.Errorf("row %v value %v is not %v row %v value %v", row, data, monotonicRelation(isStrict), lastRow, formatFloat(last)))
+						}
+						lastRow, last = row, current
+					}),
+					Close: func() []error {
+						return checkErrorSlice
+					},
+				}, nil
+			},
+		},
+		RULE_NON_EMPTY: {
+			CheckParam: noRuleParam,
+			NewCheck: func(r *Rule, format map[string]int, field string) (*columnCheck, error) {
+				index := format[field]
+				emptyRowSlice := make([]int, 0)
+				return &columnCheck{
+					CheckRow: func(row int, rowDataSlice []interface{}) {
+						if index >= len(rowDataSlice) || isEmptyData(rowDataSlice[index]) {
+							emptyRowSlice = append(emptyRowSlice, row)
+						}
+					},
+					Close: func() []error {
+						if len(emptyRowSlice) != 0 {
+							return []error{fmt.Errorf("rows %v are empty", emptyRowSlice)}
+						}
+						return nil
+					},
+				}, nil
+			},
+		},
+		RULE_SUM_PER_GROUP: {
+			CheckParam: func(r *Rule) error {
+				for key, value := range r.ParamMap {
+					if key != "by" || len(value) == 0 {
+						return fmt.Errorf("param '%v' is invalid, expect by=field", key)
+					}
+				}
+				if _, err := strconv.ParseFloat(r.Arg, 64); err != nil {
+					return fmt.Errorf("expect sum after '=': %v", err)
+				}
+				return nil
+			},
+			NewCheck: func(r *Rule, format map[string]int, field string) (*columnCheck, error) {
+				expect, _ := strconv.ParseFloat(r.Arg, 64)
+				by, hasBy := r.ParamMap["by"]
+				byIndex, hasByField := format[by]
+				if hasBy && !hasByField {
+					return nil, fmt.Errorf("group field %v does not exist in Format", by)
+				}
+
+				checkErrorSlice := make([]error, 0)
+				groupSlice := make([]string, 0)
+				groupSumMap := make(map[string]float64)
+				index := format[field]
+				return &columnCheck{
+					CheckRow: func(row int, rowDataSlice []interface{}) {
+						if index >= len(rowDataSlice) || isEmptyData(rowDataSlice[index]) {
+							return
+						}
+						f, err := dataToFloat(rowDataSlice[index])
+						if err != nil {
+							checkErrorSlice = append(checkErrorSlice, fmt.Errorf("row %v: %v", row, err))
+							return
+						}
+						group := ""
+						if hasBy && byIndex < len(rowDataSlice) {
+							group = utility.CellContent(rowDataSlice[byIndex])
+						}
+						if _, hasGroup := groupSumMap[group]; !hasGroup {
+							groupSlice = append(groupSlice, group)
+						}
+						groupSumMap[group] += f
+					},
+					Close: func() []error {
+						for _, group := range groupSlice {
+							sum := groupSumMap[group]
+							if math.Abs(sum-expect) <= 1e-9*math.Max(1, math.Abs(expect)) {
+								continue
+							}
+							if hasBy {
+								checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v %v sum %v is not equal %v", by, group, formatFloat(sum), r.Arg))
+							} else {
+								checkErrorSlice = append(checkErrorSlice, fmt.Errorf("sum %v is not equal %v", formatFloat(sum), r.Arg))
+							}
+						}
+						return checkErrorSlice
+					},
+				}, nil
+			},
+		},
+	}
+}
+
+// 导出时对有 rules(...) 的表边读取边执行列规则
+func init() {
+	if err := utility.RegisterTableChecker(&utility.TableChecker{
+		Name: "rules",
+		Accept: func(name string, formationMap map[string]string) bool {
+			for _, content := range formationMap {
+				if len(TraitRules(content)) != 0 {
+					return true
+				}
+			}
+			return false
+		},
+		NewRowChecker: newTableRulesChecker,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// newTableRulesChecker 按导出的表头为 formation 行中声明的列规则创建逐行检查
+func newTableRulesChecker(table *utility.Table) *utility.RowChecker {
+	fieldSlice := make([]string, 0, len(table.FormationMap))
+	for field := range table.FormationMap {
+		fieldSlice = append(fieldSlice, field)
+	}
+	sort.Strings(fieldSlice)
+	checkErrorSlice := make([]error, 0)
+	rulesCheckSlice := make([]*rulesCheck, 0)
+	for _, field := range fieldSlice {
+		r, err := NewRules(table.Name, field, table.FormationMap[field])
+		if err != nil {
+			checkErrorSlice = append(checkErrorSlice, err)
+			continue
+		}
+		if r == nil {
+			continue
+		}
+		c, newCheckErrorSlice := r.newCheck(table.Format)
+		checkErrorSlice = append(checkErrorSlice, newCheckErrorSlice...)
+		if c != nil {
+			rulesCheckSlice = append(rulesCheckSlice, c)
+		}
+	}
+	row := 0
+	return &utility.RowChecker{
+		CheckRow: func(rowDataSlice []interface{}) {
+			for _, c := range rulesCheckSlice {
+				c.CheckRow(row, rowDataSlice)
+			}
+			row++
+		},
+		Close: func() []error {
+			for _, c := range rulesCheckSlice {
+				checkErrorSlice = append(checkErrorSlice, c.Close()...)
+			}
+			return checkErrorSlice
+		},
+	}
+}
+
+// TraitRules 取出单元格中 rules(...) 的内容，没有时返回空字符串
+func TraitRules(c string) string {
+	start, end := traitCallLocation(c, "rules")
+	if start == -1 {
+		return ""
+	}
+	return c[start+len("rules(") : end-1]
+}
+
+// NewRules 解析 formation 行单元格中的 rules(...)，单元格中没有 rules 时返回 nil
+func NewRules(file, field, content string) (*Rules, error) {
+	rulesValue := TraitRules(content)
+	if len(rulesValue) == 0 {
+		return nil, nil
+	}
+	rulesValueWithoutSpace, err := TrimSpaceInString(rulesValue)
+	if err != nil {
+		return nil, fmt.Errorf("%v.%v trim space in string but occurs error: %v", file, field, err)
+	}
+
+	r := &Rules{File: file, Field: field}
+	for _, c := range splitTopLevel(rulesValueWithoutSpace, ',') {
+		rule, err := parseRule(c)
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v rule '%v': %v", file, field, c, err)
+		}
+		r.RuleSlice = append(r.RuleSlice, rule)
+	}
+	return r, nil
+}
+
+// Check 对 File 表中 Field 列执行所有规则
+func (r *Rules) Check(gameDataJsonObjectMap map[string]*GameDataJsonObject) []error {
+	gameDataJsonObject, hasGameDataJsonObject := gameDataJsonObjectMap[r.File]
+	if gameDataJsonObject == nil || !hasGameDataJsonObject {
+		return []error{fmt.Errorf("file %v game data json object is nil", r.File)}
+	}
+	c, checkErrorSlice := r.newCheck(gameDataJsonObject.Format)
+	if c == nil {
+		return checkErrorSlice
+	}
+	for row, rowDataSlice := range gameDataJsonObject.Data {
+		c.CheckRow(row, rowDataSlice)
+	}
+	return append(checkErrorSlice, c.Close()...)
+}
+
+// rulesCheck 逐行执行一列的所有规则
+type rulesCheck struct {
+	rules      *Rules
+	ruleSlice  []*Rule
+	checkSlice []*columnCheck
+}
+
+// newCheck 为 format 中的 Field 列创建所有规则的逐行检查，列不存在时返回 nil，无法创建的规则返回错误
+func (r *Rules) newCheck(format map[string]int) (*rulesCheck, []error) {
+	if _, hasField := format[r.Field]; !hasField {
+		return nil, []error{fmt.Errorf("file %v field %v does not exist in Format", r.File, r.Field)}
+	}
+	checkErrorSlice := make([]error, 0)
+	c := &rulesCheck{rules: r}
+	for _, rule := range r.RuleSlice {
+		check, err := columnRuleMap[rule.Name].NewCheck(rule, format, r.Field)
+		if err != nil {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v.%v rule %v: %v", r.File, r.Field, rule, err))
+			continue
+		}
+		c.ruleSlice = append(c.ruleSlice, rule)
+		c.checkSlice = append(c.checkSlice, check)
+	}
+	return c, checkErrorSlice
+}
+
+func (c *rulesCheck) CheckRow(row int, rowDataSlice []interface{}) {
+	for _, check := range c.checkSlice {
+		check.CheckRow(row, rowDataSlice)
+	}
+}
+
+func (c *rulesCheck) Close() []error {
+	checkErrorSlice := make([]error, 0)
+	for index, check := range c.checkSlice {
+		for _, err := range check.Close() {
+			checkErrorSlice = append(checkErrorSlice, fmt.Errorf("%v.%v rule %v: %v", c.rules.File, c.rules.Field, c.ruleSlice[index], err))
+		}
+	}
+	return checkErrorSlice
+}
+
+func parseRule(c string) (*Rule, error) {
+	subMatchSlice := ruleRegexp.FindStringSubmatch(c)
+	if len(subMatchSlice) == 0 {
+		return nil, fmt.Errorf("expect name, name(param) or name(param)=value")
+	}
+	rule := &Rule{Formation: c, Name: subMatchSlice[1], ParamMap: make(map[string]string), Arg: subMatchSlice[5]}
+	if len(subMatchSlice[3]) != 0 {
+		for _, param := range strings.Split(subMatchSlice[3], ",") {
+			key, value := param, ""
+			if index := strings.IndexRune(param, '='); index != -1 {
+				key, value = param[:index], param[index+1:]
+			}
+			if len(key) == 0 {
+				return nil, fmt.Errorf("param '%v' has no name", param)
+			}
+			rule.ParamMap[key] = value
+		}
+	}
+	columnRule, hasColumnRule := columnRuleMap[rule.Name]
+	if !hasColumnRule {
+		return nil, fmt.Errorf("unknown rule '%v'", rule.Name)
+	}
+	if err := columnRule.CheckParam(rule); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// splitTopLevel 按不在括号中的分隔符拆分
+func splitTopLevel(c string, separator byte) []string {
+	partSlice := make([]string, 0)
+	depth, start := 0, 0
+	for index := 0; index < len(c); index++ {
+		switch c[index] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case separator:
+			if depth == 0 {
+				partSlice = append(partSlice, c[start:index])
+				start = index + 1
+			}
+		default:
+		}
+	}
+	return append(partSlice, c[start:])
+}
+
+func noRuleParam(r *Rule) error {
+	if len(r.ParamMap) != 0 {
+		return fmt.Errorf("does not accept param")
+	}
+	return checkNoRuleArg(r)
+}
+
+func checkNoRuleArg(r *Rule) error {
+	if len(r.Arg) != 0 {
+		return fmt.Errorf("does not accept value '%v'", r.Arg)
+	}
+	return nil
+}
+
+// columnDataHandle 返回只把列中不为空的值交给 handle 的 CheckRow
+func columnDataHandle(index int, handle func(row int, data interface{})) func(row int, rowDataSlice []interface{}) {
+	return func(row int, rowDataSlice []interface{}) {
+		if index >= len(rowDataSlice) || isEmptyData(rowDataSlice[index]) {
+			return
+		}
+		handle(row, rowDataSlice[index])
+	}
+}
+
+func isEmptyData(data interface{}) bool {
+	return utility.CellContent(data) == ""
+}
+
+// dataToFloat 将 json 解析出的数字、导出时解析出的数字或数字字符串转为 float64
+func dataToFloat(data interface{}) (float64, error) {
+	switch d := data.(type) {
+	case float64:
+		return d, nil
+	case int:
+		return float64(d), nil
+	case int64:
+		return float64(d), nil
+	case uint64:
+		return float64(d), nil
+	case json.Number:
+		return d.Float64()
+	case string:
+		if f, err := strconv.ParseFloat(d, 64); err == nil {
+			return f, nil
+		}
+	default:
+	}
+	return 0, fmt.Errorf("value '%v' is not a number", data)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+func monotonicRelation(isStrict bool) string {
+	if isStrict {
+		return "greater than"
+	}
+	return "greater than or equal"
+}
+
+// GetRuleNameSlice 返回支持的列规则名字
+func GetRuleNameSlice() []string {
+	ruleNameSlice := make([]string, 0, len(columnRuleMap))
+	for name := range columnRuleMap {
+		ruleNameSlice = append(ruleNameSlice, name)
+	}
+	sort.Strings(ruleNameSlice)
+	return ruleNameSlice
+}
@@ -0,0 +1,97 @@
+package formation
+
+import (
+	"encoding/csv"
+	"go-formation/utility"
+	"strings"
+	"testing"
+)
+
+const testPoolJson = `{"Format":{"pool_id":0,"level":1,"weight":2,"name":3},"Data":[[1,1,6000,"a"],[1,2,4000,""],[2,2,5000,"c"],[2,5,4000,"c"]]}`
+
+func TestRulesCheck(t *testing.T) {
+	gameDataJsonObjectMap := map[string]*GameDataJsonObject{"Pool": testGameDataJsonObject(t, testPoolJson)}
+	for _, c := range []struct {
+		field            string
+		content          string
+		errorContainText []string
+	}{
+		{"level", "rules(unique)", []string{"value 2 is duplicated in rows [1 2]"}},
+		{"level", "rules(monotonic)", nil},
+		{"level", "rules(monotonic(strict))", []string{"row 2 value 2 is not greater than row 1 value 2"}},
+		{"name", "rules(non-empty)", []string{"rows [1] are empty"}},
+		{"name", "rules(unique)", []string{"value c is duplicated in rows [2 3]"}},
+		{"weight", "rules(sum-per-group(by=pool_id)=10000)", []string{"pool_id 2 sum 9000 is not equal 10000"}},
+		{"weight", "rules(sum-per-group=19000)", nil},
+		{"weight", "rules(sum-per-group(by=missing)=10000)", []string{"group field missing does not exist"}},
+		{"weight", "format(A.b) rules( unique , monotonic )", []string{"rule unique", "rule monotonic: row 1", "rule monotonic: row 3"}},
+		{"missing", "rules(unique)", []string{"field missing does not exist"}},
+	} {
+		r, err := NewRules("Pool", c.field, c.content)
+		if err != nil {
+			t.Errorf("NewRules(%q) error: %v", c.content, err)
+			continue
+		}
+		checkErrorSlice := r.Check(gameDataJsonObjectMap)
+		if len(checkErrorSlice) != len(c.errorContainText) {
+			t.Errorf("%v %v Check = %v, expect %v errors", c.field, c.content, checkErrorSlice, len(c.errorContainText))
+			continue
+		}
+		for i, text := range c.errorContainText {
+			if !strings.Contains(checkErrorSlice[i].Error(), text) {
+				t.Errorf("%v %v Check error = %v, expect contain %v", c.field, c.content, checkErrorSlice[i], text)
+			}
+		}
+	}
+}
+
+func TestNewRules(t *testing.T) {
+	for _, c := range []struct {
+		content string
+		isNil   bool
+		isError bool
+	}{
+		{"format(A.b)", true, false},
+		{"rules(unique,sum-per-group(by=pool_id)=100)", false, false},
+		{"rules(nope)", false, true},
+		{"rules(unique(x))", false, true},
+		{"rules(unique=1)", false, true},
+		{"rules(sum-per-group(by=pool_id))", false, true},
+		{"rules(=1)", false, true},
+	} {
+		r, err := NewRules("Pool", "weight", c.content)
+		if c.isError != (err != nil) || (!c.isError && c.isNil != (r == nil)) {
+			t.Errorf("NewRules(%q) = %v, %v", c.content, r, err)
+		}
+	}
+}
+
+func TestCheckGameDataRules(t *testing.T) {
+	gameDataJsonObjectMap := map[string]*GameDataJsonObject{"Pool": testGameDataJsonObject(t, testPoolJson)}
+	fileFormationMap := map[string]map[string]string{"Pool": {"level": "rules(monotonic(strict))", "weight": "rules(nope)"}}
+	checkErrorSlice, _ := CheckGameData(gameDataJsonObjectMap, fileFormationMap, nil)
+	if len(checkErrorSlice) != 2 || !strings.Contains(checkErrorSlice[0].Error(), "monotonic(strict)") || !strings.Contains(checkErrorSlice[1].Error(), "unknown rule") {
+		t.Errorf("CheckGameData rules = %v", checkErrorSlice)
+	}
+}
+
+func TestExportRules(t *testing.T) {
+	csvContent := "comment,comment,comment\n,,rules(sum-per-group(by=pool_id)=100)\nserver,server,server\npool_id,item_id,weight\nint,int,uint\n1,1001,60\n1,1002,%v\n"
+	for _, c := range []struct {
+		weight  string
+		isError bool
+	}{
+		{"40", false},
+		{"30", true},
+	} {
+		content := strings.Replace(csvContent, "%v", c.weight, 1)
+		err, _ := utility.ProcessCsvWithName("TestExportRules", csv.NewReader(strings.NewReader(content)))
+		if c.isError != (err != nil) {
+			t.Errorf("ProcessCsv weight %v error = %v", c.weight, err)
+		}
+		_, _, err = utility.ProcessCsvAndFormationForTargetsWithName("TestExportRules", csv.NewReader(strings.NewReader(content)), utility.DefaultHeaderLayout, []utility.ExportTarget{utility.EXPORT_SERVER, utility.EXPORT_CLIENT})
+		if c.isError != (err != nil) {
+			t.Errorf("ProcessCsvAndFormationForTargets weight %v error = %v", c.weight, err)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package formation
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type ValueKind int
+
+const (
+	// VALUE_SCALAR 单个值，对应 FullstopNode 或 PH
+	VALUE_SCALAR ValueKind = iota + 1
+	// VALUE_TUPLE 各位置含义不同的一组值，对应子节点不相同的 CommaNode
+	VALUE_TUPLE
+	// VALUE_LIST 重复的一组值，对应子节点都相同的 CommaNode 或 SemicolonNode
+	VALUE_LIST
+)
+
+// Value 按 formation 解析单元格得到的值树
+type Value struct {
+	Kind ValueKind
+	// Name 引用的字段名，占位符按在元组中的顺序命名为 ph1、ph2...
+	Name string
+	// Ref 引用的 File.field，占位符为空
+	Ref string
+	// Scalar 为 int64、float64 或 string
+	Scalar interface{}
+	Items  []*Value
+	// AnnotationSlice 槽位或组上的注解
+	AnnotationSlice []*Annotation
+}
+
+// Interface 转为 json 可直接序列化的值，元组转为以 Name 为键的 map
+func (v *Value) Interface() interface{} {
+	if v == nil {
+		return nil
+	}
+	switch v.Kind {
+	case VALUE_TUPLE:
+		r := make(map[string]interface{}, len(v.Items))
+		for _, item := range v.Items {
+			r[item.Name] = item.Interface()
+		}
+		return r
+	case VALUE_LIST:
+		r := make([]interface{}, 0, len(v.Items))
+		for _, item := range v.Items {
+			r = append(r, item.Interface())
+		}
+		return r
+	default:
+	}
+	return v.Scalar
+}
+
+// Item 按名字查找元组中的值
+func (v *Value) Item(name string) *Value {
+	for _, item := range v.Items {
+		if item.Name == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// RenamePlaceHolder 依次用 nameSlice 替换所有元组中 ph1、ph2... 的名字，名字为空字符串的占位符保留原名，
+// 元组中的占位符比 nameSlice 多时返回错误，避免同一个对象中混用命名的键与 ph 键
+func (v *Value) RenamePlaceHolder(nameSlice []string) error {
+	if v == nil || len(nameSlice) == 0 {
+		return nil
+	}
+	placeHolderCount := 0
+	for _, item := range v.Items {
+		if v.Kind == VALUE_TUPLE && len(item.Ref) == 0 {
+			placeHolderCount++
+			if placeHolderCount > len(nameSlice) {
+				return fmt.Errorf("tuple has more than %v placeholders, only names %v are given", len(nameSlice), nameSlice)
+			}
+			if len(nameSlice[placeHolderCount-1]) != 0 {
+				item.Name = nameSlice[placeHolderCount-1]
+			}
+			continue
+		}
+		if err := item.RenamePlaceHolder(nameSlice); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseScalar 单元格中的值没有类型信息，能解析为整数或浮点数并且按 formatScalar 输出后与原文相同时按数字处理，
+// 01001、1e3、1.50 等无法原样输出的文本保留为字符串
+func parseScalar(c string) interface{} {
+	if i, err := strconv.ParseInt(c, 10, 64); err == nil && strconv.FormatInt(i, 10) == c {
+		return i
+	}
+	if f, err := strconv.ParseFloat(c, 64); err == nil && strconv.FormatFloat(f, 'f', -1, 64) == c {
+		return f
+	}
+	return c
+}
+
+// formatScalar 输出单个值，值中不能含有单元格的分隔符，否则无法按原样解析回来
+func formatScalar(scalar interface{}) (string, error) {
+	var c string
+	switch s := scalar.(type) {
+	case nil:
+		c = ""
+	case string:
+		c = s
+	case int64:
+		c = strconv.FormatInt(s, 10)
+	case float64:
+		c = strconv.FormatFloat(s, 'f', -1, 64)
+	default:
+		c = fmt.Sprintf("%v", s)
+	}
+	if strings.ContainsAny(c, separatorOf(COMMA)+separatorOf(SEMICOLON)+separatorOf(PERPENDICULAR)) {
+		return "", fmt.Errorf("value '%v' contains separator", c)
+	}
+	return c, nil
+}
+
+// EncodeValue 将 Go 的值转为值树，DecodeValue 的逆过程：切片转为列表，结构体按导出字段的顺序转为元组，
+// 字段名取 `formation:"name"` 标签，没有标签时取字段名
+func EncodeValue(in interface{}) (*Value, error) {
+	if v, isValue := in.(*Value); isValue {
+		return v, nil
+	}
+	return encodeValue(reflect.ValueOf(in))
+}
+
+func encodeValue(rv reflect.Value) (*Value, error) {
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return &Value{Kind: VALUE_SCALAR, Scalar: ""}, nil
+	case reflect.Ptr, reflect.Interface:
+		if rv.IsNil() {
+			return &Value{Kind: VALUE_SCALAR, Scalar: ""}, nil
+		}
+		return encodeValue(rv.Elem())
+	case reflect.Slice, reflect.Array:
+		v := &Value{Kind: VALUE_LIST}
+		for i := 0; i < rv.Len(); i++ {
+			item, err := encodeValue(rv.Index(i))
+			if err != nil {
+				return nil, fmt.Errorf("[%v]: %v", i, err)
+			}
+			v.Items = append(v.Items, item)
+		}
+		return v, nil
+	case reflect.Struct:
+		v := &Value{Kind: VALUE_TUPLE}
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			if len(field.PkgPath) != 0 || field.Tag.Get("formation") == "-" {
+				continue
+			}
+			item, err := encodeValue(rv.Field(i))
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", field.Name, err)
+			}
+			item.Name = field.Name
+			if name := field.Tag.Get("formation"); len(name) != 0 {
+				item.Name = name
+			}
+			v.Items = append(v.Items, item)
+		}
+		return v, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Value{Kind: VALUE_SCALAR, Scalar: rv.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Value{Kind: VALUE_SCALAR, Scalar: strconv.FormatUint(rv.Uint(), 10)}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Value{Kind: VALUE_SCALAR, Scalar: rv.Float()}, nil
+	case reflect.String:
+		return &Value{Kind: VALUE_SCALAR, Scalar: rv.String()}, nil
+	case reflect.Bool:
+		return &Value{Kind: VALUE_SCALAR, Scalar: strconv.FormatBool(rv.Bool())}, nil
+	default:
+	}
+	return nil, fmt.Errorf("can not encode %v to value", rv.Type())
+}
+
+// nameTupleItem 为元组中的值命名，同名引用追加序号区分
+func nameTupleItem(itemSlice []*Value) {
+	placeHolderCount := 0
+	nameCountMap := make(map[string]int)
+	for _, item := range itemSlice {
+		if len(item.Ref) == 0 {
+			placeHolderCount++
+			item.Name = fmt.Sprintf("ph%v", placeHolderCount)
+			continue
+		}
+		nameCountMap[item.Name]++
+		if nameCountMap[item.Name] > 1 {
+			item.Name = fmt.Sprintf("%v%v", item.Name, nameCountMap[item.Name])
+		}
+	}
+}
+
+// DecodeValue 将值树解码到 out 指向的变量：列表解码到切片，元组解码到结构体，
+// 结构体字段有 `formation:"name"` 标签时按名字匹配，否则按导出字段的顺序匹配
+func DecodeValue(v *Value, out interface{}) error {
+	rv := reflect.ValueOf(out)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("decode value out must be non-nil pointer, got %T", out)
+	}
+	return decodeValue(v, rv.Elem())
+}
+
+func decodeValue(v *Value, rv reflect.Value) error {
+	if v == nil {
+		return nil
+	}
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			rv.Set(reflect.New(rv.Type().Elem()))
+		}
+		return decodeValue(v, rv.Elem())
+	case reflect.Interface:
+		if rv.NumMethod() != 0 {
+			return fmt.Errorf("can not decode value to %v", rv.Type())
+		}
+		rv.Set(reflect.ValueOf(v.Interface()))
+		return nil
+	case reflect.Slice:
+		itemSlice := v.Items
+		if v.Kind == VALUE_SCALAR {
+			itemSlice = []*Value{v}
+		}
+		s := reflect.MakeSlice(rv.Type(), len(itemSlice), len(itemSlice))
+		for i, item := range itemSlice {
+			if err := decodeValue(item, s.Index(i)); err != nil {
+				return fmt.Errorf("[%v]: %v", i, err)
+			}
+		}
+		rv.Set(s)
+		return nil
+	case reflect.Struct:
+		if v.Kind != VALUE_TUPLE {
+			return fmt.Errorf("can not decode %v to struct %v", v.Interface(), rv.Type())
+		}
+		position := 0
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			if len(field.PkgPath) != 0 {
+				continue
+			}
+			var item *Value
+			if name, hasTag := field.Tag.Lookup("formation"); hasTag {
+				if name == "-" {
+					continue
+				}
+				item = v.Item(name)
+			} else if position < len(v.Items) {
+				item = v.Items[position]
+			}
+			position++
+			if err := decodeValue(item, rv.Field(i)); err != nil {
+				return fmt.Errorf("%v: %v", field.Name, err)
+			}
+		}
+		return nil
+	default:
+	}
+
+	if v.Kind != VALUE_SCALAR {
+		return fmt.Errorf("can not decode %v to %v", v.Interface(), rv.Type())
+	}
+	c := fmt.Sprintf("%v", v.Scalar)
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(c)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(c, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(c, 10, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(c, rv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		rv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.ToLower(c))
+		if err != nil {
+			return err
+		}
+		rv.SetBool(b)
+	default:
+		return fmt.Errorf("can not decode %v to %v", v.Scalar, rv.Type())
+	}
+	return nil
+}
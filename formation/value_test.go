@@ -0,0 +1,177 @@
+package formation
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func testNewFormation(t *testing.T, formation string) *Formation {
+	return testNewFormationOf(t, "A", "x", formation)
+}
+
+func testNewFormationOf(t *testing.T, file, field, formation string) *Formation {
+	f, err := NewFormation(file, field, formation)
+	if err != nil {
+		t.Fatalf("NewFormation(%q): %v", formation, err)
+	}
+	return f
+}
+
+func TestParseValue(t *testing.T) {
+	for _, c := range []struct {
+		formation string
+		refValue  string
+		content   string
+		expect    string
+		isError   bool
+	}{
+		{"format(A.b)", "", "1001", `1001`, false},
+		{"format(A.b,PH)", "", "1001,10", `{"b":1001,"ph1":10}`, false},
+		{"format(A.b,PH;A.b,PH)", "", "1001,10;1002,20", `[{"b":1001,"ph1":10},{"b":1002,"ph1":20}]`, false},
+		{"format(A.b,A.b)", "", "1001,1002", `[1001,1002]`, false},
+		{"format(A.b;A.b)", "", "1001;1002", `[1001,1002]`, false},
+		{"format(A.b,PH,B.c,PH)", "", "1001,x,2,0.5", `{"b":1001,"c":2,"ph1":"x","ph2":0.5}`, false},
+		{"format(A.b,PH,B.c)", "", "1001,,2", `{"b":1001,"c":2,"ph1":""}`, false},
+		{"format(A.t(1):B.c,PH|A.t(2):C.d)", "1", "3,4", `{"c":3,"ph1":4}`, false},
+		{"format(A.t(1):B.c,PH|A.t(2):C.d)", "2", "5", `5`, false},
+		{"format(A.t(1):B.c,PH|A.t(2):C.d)", "3", "5", ``, true},
+		{"format(A.b,PH)", "", "1001", ``, true},
+		{"format(A.b,PH)", "", "1001,10,3", ``, true},
+		{"format(A.b,PH;A.b,PH)", "", "1001,10;1002", ``, true},
+	} {
+		v, err := testNewFormation(t, c.formation).ParseValue(c.refValue, c.content)
+		if c.isError {
+			if err == nil {
+				t.Errorf("%v ParseValue(%q, %q) expect error", c.formation, c.refValue, c.content)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v ParseValue(%q, %q) error: %v", c.formation, c.refValue, c.content, err)
+			continue
+		}
+		if b, _ := json.Marshal(v.Interface()); string(b) != c.expect {
+			t.Errorf("%v ParseValue(%q, %q) = %s, expect %s", c.formation, c.refValue, c.content, b, c.expect)
+		}
+	}
+}
+
+func TestDecodeValue(t *testing.T) {
+	type reward struct {
+		GroupID int
+		Count   int
+	}
+	v, err := testNewFormation(t, "format(A.b,PH;A.b,PH)").ParseValue("", "1001,10;1002,20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rewardSlice := make([]reward, 0)
+	if err := DecodeValue(v, &rewardSlice); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []reward{{1001, 10}, {1002, 20}}; !reflect.DeepEqual(rewardSlice, expect) {
+		t.Errorf("DecodeValue = %v, expect %v", rewardSlice, expect)
+	}
+
+	type namedReward struct {
+		Count   int32  `formation:"ph1"`
+		GroupID uint64 `formation:"b"`
+		Ignore  string `formation:"-"`
+	}
+	namedRewardSlice := make([]*namedReward, 0)
+	if err := DecodeValue(v, &namedRewardSlice); err != nil {
+		t.Fatal(err)
+	}
+	if len(namedRewardSlice) != 2 || *namedRewardSlice[1] != (namedReward{Count: 20, GroupID: 1002}) {
+		t.Errorf("DecodeValue by tag = %v", namedRewardSlice)
+	}
+
+	var groupID int8
+	if err := DecodeValue(v.Items[0].Item("b"), &groupID); err == nil {
+		t.Errorf("DecodeValue 1001 to int8 expect error")
+	}
+	if err := DecodeValue(v, rewardSlice); err == nil {
+		t.Errorf("DecodeValue to non-pointer expect error")
+	}
+}
+
+func TestFormatRoundTrip(t *testing.T) {
+	for _, c := range []struct {
+		formation string
+		refValue  string
+		content   string
+	}{
+		{"format(A.b)", "", "1001"},
+		{"format(A.b)", "", "01001"},
+		{"format(A.b)", "", "1e3"},
+		{"format(A.b)", "", "1.50"},
+		{"format(A.b)", "", "-0"},
+		{"format(A.b)", "", "+1"},
+		{"format(A.b)", "", "0.1"},
+		{"format(A.b,PH;A.b,PH)", "", "01001,10;1002,1e3"},
+		{"format(A.b,PH,B.c)", "", "1001,,2"},
+		{"format(A.t(1):B.c,PH|A.t(2):C.d)", "1", "3,0004"},
+	} {
+		f := testNewFormation(t, c.formation)
+		v, err := f.ParseValue(c.refValue, c.content)
+		if err != nil {
+			t.Errorf("%v ParseValue(%q) error: %v", c.formation, c.content, err)
+			continue
+		}
+		content, err := f.Format(c.refValue, v)
+		if err != nil {
+			t.Errorf("%v Format(%q) error: %v", c.formation, c.content, err)
+			continue
+		}
+		if content != c.content {
+			t.Errorf("%v Format(ParseValue(%q)) = %q", c.formation, c.content, content)
+		}
+	}
+}
+
+func TestParseScalar(t *testing.T) {
+	for _, c := range []struct {
+		content string
+		expect  interface{}
+	}{
+		{"1001", int64(1001)},
+		{"-5", int64(-5)},
+		{"0.5", 0.5},
+		{"01001", "01001"},
+		{"1e3", "1e3"},
+		{"1.50", "1.50"},
+		{"abc", "abc"},
+		{"", ""},
+	} {
+		if scalar := parseScalar(c.content); scalar != c.expect {
+			t.Errorf("parseScalar(%q) = %#v, expect %#v", c.content, scalar, c.expect)
+		}
+	}
+}
+
+func TestEncodeValueFormat(t *testing.T) {
+	type reward struct {
+		GroupID int64
+		Count   float64
+	}
+	f := testNewFormation(t, "format(A.b,PH;A.b,PH)")
+	v, err := EncodeValue([]reward{{1001, 10}, {1002, 0.5}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, err := f.Format("", v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if content != "1001,10;1002,0.5" {
+		t.Errorf("Format(EncodeValue) = %q", content)
+	}
+
+	if v, err = EncodeValue([]string{"a,b"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := testNewFormation(t, "format(A.b;A.b)").Format("", v); err == nil {
+		t.Errorf("Format value containing separator expect error")
+	}
+}
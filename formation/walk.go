@@ -0,0 +1,40 @@
+package formation
+
+// Visitor 与 go/ast 的 Visitor 相同：Walk 对每个节点调用 Visit，返回的 Visitor 非 nil 时继续访问子节点，
+// 子节点访问完后以 nil 调用 Visit
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk 深度优先遍历以 node 为根的节点树
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+	for _, child := range node.Children() {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect 深度优先遍历节点树，f 返回 false 时不再访问该节点的子节点，子节点访问完后以 nil 调用 f
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// Root 返回 formation 的根节点，有分类时为 PerpendicularNode
+func (f *Formation) Root() Node {
+	if f.HasDecoration {
+		return f.DecorationNode
+	}
+	return f.FormationNode
+}
@@ -0,0 +1,69 @@
+package formation
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// testWalkVisitor 记录访问顺序，depth 达到 maxDepth 时不再访问子节点
+type testWalkVisitor struct {
+	visitSlice *[]string
+	depth      int
+	maxDepth   int
+}
+
+func (v *testWalkVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		*v.visitSlice = append(*v.visitSlice, "end")
+		return nil
+	}
+	*v.visitSlice = append(*v.visitSlice, node.GetFormation())
+	if v.depth+1 >= v.maxDepth {
+		return nil
+	}
+	return &testWalkVisitor{visitSlice: v.visitSlice, depth: v.depth + 1, maxDepth: v.maxDepth}
+}
+
+func TestWalk(t *testing.T) {
+	for _, c := range []struct {
+		formation string
+		maxDepth  int
+		expect    []string
+	}{
+		{"format(A.b)", 10, []string{"A.b", "end"}},
+		{"format(A.b,PH;A.b,PH)", 10, []string{"A.b,PH;A.b,PH", "A.b,PH", "A.b", "end", "PH", "end", "end", "end"}},
+		{"format(A.b,PH;A.b,PH)", 2, []string{"A.b,PH;A.b,PH", "A.b,PH", "end"}},
+		{"format(A.b,A.b)", 10, []string{"A.b,A.b", "A.b", "end", "end"}},
+		{"format(A.t(1):B.c|A.t(2):C.d)", 10, []string{
+			"A.t(1):B.c|A.t(2):C.d",
+			"A.t(1):B.c", "A.t(1)", "A.t", "end", "end", "B.c", "end", "end",
+			"A.t(2):C.d", "A.t(2)", "A.t", "end", "end", "C.d", "end", "end",
+			"end",
+		}},
+		{"format(A.t(1):B.c|A.t(2):C.d)", 1, []string{"A.t(1):B.c|A.t(2):C.d"}},
+	} {
+		visitSlice := make([]string, 0)
+		Walk(&testWalkVisitor{visitSlice: &visitSlice, maxDepth: c.maxDepth}, testNewFormation(t, c.formation).Root())
+		if !reflect.DeepEqual(visitSlice, c.expect) {
+			t.Errorf("Walk %v max depth %v = %q, expect %q", c.formation, c.maxDepth, visitSlice, c.expect)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	f := testNewFormation(t, "format(A.t(1):B.c,PH|A.t(2):C.d)")
+	refSlice := make([]string, 0)
+	Inspect(f.Root(), func(node Node) bool {
+		if _, isBrackets := node.(*BracketsNode); isBrackets {
+			return false
+		}
+		if n, isFullstop := node.(*FullstopNode); isFullstop && !n.IsPlaceHolder {
+			refSlice = append(refSlice, fmt.Sprintf("%v.%v", n.GetKey(), n.GetValue()))
+		}
+		return true
+	})
+	if expect := []string{"B.c", "C.d"}; !reflect.DeepEqual(refSlice, expect) {
+		t.Errorf("Inspect references = %v, expect %v", refSlice, expect)
+	}
+}
@@ -3,10 +3,37 @@ package main
 import (
 	"fmt"
 	"go-formation/formation"
+	"go-formation/utility"
+	"os"
+	"sort"
 )
 
+// 子命令，返回进程退出码
+var commandMap = map[string]func([]string) int{
+	"fmt":      runFmt,
+	"dump-ast": runDumpAst,
+	"check":    runCheck,
+}
+
 func main() {
-	testDecoration()
+	// formation 与 utility 默认不输出诊断信息，示例输出到 stdout，子命令的 stdout 留给结果
+	if len(os.Args) < 2 {
+		utility.SetReporter(utility.NewWriterReporter(os.Stdout))
+		testDecoration()
+		return
+	}
+	utility.SetReporter(utility.NewWriterReporter(os.Stderr))
+	command, hasCommand := commandMap[os.Args[1]]
+	if !hasCommand {
+		commandNameSlice := make([]string, 0, len(commandMap))
+		for commandName := range commandMap {
+			commandNameSlice = append(commandNameSlice, commandName)
+		}
+		sort.Strings(commandNameSlice)
+		fmt.Fprintf(os.Stderr, "unknown command %v, available commands: %v\n", os.Args[1], commandNameSlice)
+		os.Exit(2)
+	}
+	os.Exit(command(os.Args[2:]))
 }
 
 func testDecoration() {
@@ -33,9 +60,9 @@ func testDecoration() {
 
 	if formation.HasDecoration(formationValueWithoutSpace) {
 		formationExample.HasDecoration = true
-		formationExample.DecorationNode = formation.ParseDecoration(formationValueWithoutSpace)
-		if formationExample.DecorationNode == nil {
-			fmt.Printf("Error: formation '%v' has decoration but decoration node is nil\n", formationValueWithoutSpace)
+		formationExample.DecorationNode, err = formation.ParseDecoration(formationValueWithoutSpace)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
 			return
 		}
 
@@ -44,7 +71,10 @@ func testDecoration() {
 		// }
 	} else {
 		formationExample.HasDecoration = false
-		formation.ParseFormation(formationValueWithoutSpace)
+		if formationExample.FormationNode, err = formation.ParseFormation(formationValueWithoutSpace); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			return
+		}
 	}
 
 	testFormation(`A.b,PH;A.b,PH`, `1001,10;1002,20;1003,30`)
@@ -70,6 +100,9 @@ func testFormation(configFormation, configContent string) {
 		configFormationNode = formation.GetNodeMatcher(formation.FULLSTOP).NewNode()
 	}
 
-	configFormationNode.ParseFormation(configFormation)
+	if err := configFormationNode.ParseFormation(configFormation); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		return
+	}
 	configFormationNode.ParseContent(configContent)
 }
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go-formation/utility"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// applyProjectFile 加载并应用项目配置，path 为空时使用默认设置
+func applyProjectFile(path string) error {
+	if len(path) == 0 {
+		return nil
+	}
+	project, err := utility.LoadProjectFile(path)
+	if err != nil {
+		return err
+	}
+	return project.Apply()
+}
+
+// readCsvRecordSlice 自动检测编码读取整个 csv 文件，允许每行的列数不同，返回检测到的编码用于按原编码写回
+func readCsvRecordSlice(path string) ([][]string, encoding.Encoding, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	e := utility.DetectEncoding(content, false)
+	recordSlice, err := parseCsvRecordSlice(transform.NewReader(bytes.NewReader(content), e.NewDecoder()))
+	if err != nil {
+		return nil, nil, err
+	}
+	return recordSlice, e, nil
+}
+
+func parseCsvRecordSlice(r io.Reader) ([][]string, error) {
+	csvReader := csv.NewReader(r)
+	csvReader.FieldsPerRecord = -1
+	return csvReader.ReadAll()
+}
+
+// csvRecordFieldRange 返回 text 中第 row 条记录（与 csv.Reader 一样跳过空行）每个字段在 text 中的字节范围，
+// 范围包括字段的引号，不包括分隔符与换行符，没有该记录时返回 nil
+func csvRecordFieldRange(text string, row int) [][2]int {
+	record := 0
+	index := 0
+	for index < len(text) {
+		if text[index] == '\n' {
+			index++
+			continue
+		}
+		if strings.HasPrefix(text[index:], "\r\n") {
+			index += 2
+			continue
+		}
+		rangeSlice := make([][2]int, 0)
+		for {
+			start := index
+			if index < len(text) && text[index] == '"' {
+				for index++; index < len(text); index++ {
+					if text[index] != '"' {
+						continue
+					}
+					if index+1 < len(text) && text[index+1] == '"' {
+						index++
+						continue
+					}
+					index++
+					break
+				}
+			}
+			for index < len(text) && text[index] != ',' && text[index] != '\n' {
+				index++
+			}
+			end := index
+			if end > start && text[end-1] == '\r' && (index == len(text) || text[index] == '\n') {
+				end--
+			}
+			rangeSlice = append(rangeSlice, [2]int{start, end})
+			if index < len(text) && text[index] == ',' {
+				index++
+				continue
+			}
+			if index < len(text) {
+				index++
+			}
+			break
+		}
+		if record == row {
+			return rangeSlice
+		}
+		record++
+	}
+	return nil
+}
+
+// quoteCsvField 将单元格写为 csv 字段，原来带引号的字段保留引号，否则只在需要时加引号
+func quoteCsvField(raw, cell string) string {
+	if strings.HasPrefix(raw, `"`) || strings.ContainsAny(cell, ",\"\r\n") || strings.HasPrefix(cell, " ") || strings.HasPrefix(cell, "\t") {
+		return `"` + strings.Replace(cell, `"`, `""`, -1) + `"`
+	}
+	return cell
+}
+
+// headerRowIndex 返回项目配置的表头布局中 role 所在的行，没有该行时返回 -1
+func headerRowIndex(role utility.HeaderRole) int {
+	for row, r := range utility.GetDefaultHeaderLayout().RoleSlice {
+		if r == role {
+			return row
+		}
+	}
+	return -1
+}
+
+func tableNameOf(path string) string {
+	return utility.TraitFileName(filepath.Base(path), filepath.Ext(path))
+}
+
+// fieldNameOf 返回第 i 列在 key 行中的字段名
+func fieldNameOf(recordSlice [][]string, i int) string {
+	keyRow := headerRowIndex(utility.HEADER_KEY)
+	if keyRow >= 0 && keyRow < len(recordSlice) && i < len(recordSlice[keyRow]) {
+		return recordSlice[keyRow][i]
+	}
+	return fmt.Sprintf("column %v", i+1)
+}
@@ -0,0 +1,76 @@
+package utility
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TableChecker 导出时对整张表执行的检查，由 formation 等依赖 utility 的包在 init() 中注册
+type TableChecker struct {
+	Name string
+	// Accept 根据表名与 formation 行判断是否需要检查
+	Accept func(name string, formationMap map[string]string) bool
+	// NewRowChecker 为接受的表创建逐行检查，table 只有表头信息，Data 为空，导出时不在内存中保留整张表
+	NewRowChecker func(table *Table) *RowChecker
+}
+
+// RowChecker 一张表的逐行检查，CheckRow 按行的顺序接收解析后的每一行，Close 在读取结束后返回所有错误
+type RowChecker struct {
+	CheckRow func(row []interface{})
+	Close    func() []error
+}
+
+var tableCheckerSlice []*TableChecker
+
+// RegisterTableChecker 注册导出时的表检查，Strict 的 TableLoader 边读取边执行
+func RegisterTableChecker(c *TableChecker) error {
+	if c == nil || c.Accept == nil || c.NewRowChecker == nil {
+		return fmt.Errorf("table checker Accept and NewRowChecker must not be nil")
+	}
+	for _, tableChecker := range tableCheckerSlice {
+		if tableChecker.Name == c.Name {
+			return fmt.Errorf("table checker %v already registered", c.Name)
+		}
+	}
+	tableCheckerSlice = append(tableCheckerSlice, c)
+	return nil
+}
+
+// tableCheck 逐行执行所有接受该表的 TableChecker
+type tableCheck struct {
+	name            string
+	rowCheckerSlice []*RowChecker
+}
+
+func newTableCheck(table *Table) *tableCheck {
+	c := &tableCheck{name: table.Name}
+	for _, tableChecker := range tableCheckerSlice {
+		if !tableChecker.Accept(table.Name, table.FormationMap) {
+			continue
+		}
+		if rowChecker := tableChecker.NewRowChecker(table); rowChecker != nil {
+			c.rowCheckerSlice = append(c.rowCheckerSlice, rowChecker)
+		}
+	}
+	return c
+}
+
+func (c *tableCheck) CheckRow(row []interface{}) {
+	for _, rowChecker := range c.rowCheckerSlice {
+		rowChecker.CheckRow(row)
+	}
+}
+
+// Close 结束所有检查，所有错误合并为一个
+func (c *tableCheck) Close() error {
+	errorStringSlice := make([]string, 0)
+	for _, rowChecker := range c.rowCheckerSlice {
+		for _, err := range rowChecker.Close() {
+			errorStringSlice = append(errorStringSlice, err.Error())
+		}
+	}
+	if len(errorStringSlice) == 0 {
+		return nil
+	}
+	return fmt.Errorf("table %v check failed: %v", c.name, strings.Join(errorStringSlice, "; "))
+}
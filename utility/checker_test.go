@@ -0,0 +1,71 @@
+package utility
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestRegisterTableChecker(t *testing.T) {
+	checkCount := 0
+	if err := RegisterTableChecker(&TableChecker{
+		Name: "test-weight",
+		Accept: func(name string, formationMap map[string]string) bool {
+			return name == "TestChecked"
+		},
+		NewRowChecker: func(table *Table) *RowChecker {
+			if table.Data != nil {
+				t.Errorf("NewRowChecker table Data = %v, expect nil", table.Data)
+			}
+			index := table.Format["item_id"]
+			sum := int64(0)
+			return &RowChecker{
+				CheckRow: func(row []interface{}) {
+					checkCount++
+					sum += row[index].(int64)
+				},
+				Close: func() []error {
+					if sum != 1000 {
+						return []error{fmt.Errorf("item_id sum %v", sum)}
+					}
+					return nil
+				},
+			}
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := RegisterTableChecker(&TableChecker{Name: "test-weight", Accept: func(string, map[string]string) bool { return false }, NewRowChecker: func(*Table) *RowChecker { return nil }}); err == nil {
+		t.Errorf("RegisterTableChecker with registered name expect error")
+	}
+	if err := RegisterTableChecker(&TableChecker{Name: "test-nil"}); err == nil {
+		t.Errorf("RegisterTableChecker without Accept and NewRowChecker expect error")
+	}
+
+	if err, _ := ProcessCsvWithName("TestChecked", csv.NewReader(strings.NewReader(testLoaderCsv))); err == nil || !strings.Contains(err.Error(), "item_id sum 2003") || checkCount != 2 {
+		t.Errorf("ProcessCsv checked table error = %v, check count %v", err, checkCount)
+	}
+	// 检查在所有行写出之后才失败，写出的 json 缺少结尾
+	buffer := &bytes.Buffer{}
+	if _, err := ProcessCsvAndFormationToWriter(buffer, "TestChecked", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout); err == nil || strings.HasSuffix(buffer.String(), "}") {
+		t.Errorf("ProcessCsvAndFormationToWriter checked table error = %v, json %q", err, buffer.String())
+	}
+	if _, _, err := ProcessCsvAndFormationForTargetsWithName("TestChecked", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout, []ExportTarget{EXPORT_SERVER}); err == nil {
+		t.Errorf("ProcessCsvAndFormationForTargets checked table expect error")
+	}
+	if err, _ := ProcessCsvWithName("TestUnchecked", csv.NewReader(strings.NewReader(testLoaderCsv))); err != nil {
+		t.Errorf("ProcessCsv unchecked table error = %v", err)
+	}
+
+	loader, err := NewTableLoader("TestChecked", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Strict = false
+	checkCount = 0
+	if _, err := loader.Load(); err != nil || checkCount != 0 {
+		t.Errorf("non-strict Load error = %v, check count %v", err, checkCount)
+	}
+}
@@ -0,0 +1,166 @@
+package utility
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 关联检查中比较值的方式，由被引用列在类型行中声明的类型决定
+const (
+	COMPARE_KIND_INT    = "int"
+	COMPARE_KIND_FLOAT  = "float"
+	COMPARE_KIND_STRING = "string"
+	// COMPARE_KIND_AUTO 类型未知时，两边都能转为整数或浮点数时按数值比较，否则按字符串比较
+	COMPARE_KIND_AUTO = ""
+)
+
+// GetCompareKind 返回类型比较时的方式，枚举按整数比较
+func GetCompareKind(Type string) string {
+	p := GetTypeParser(Type)
+	if p == nil {
+		return COMPARE_KIND_AUTO
+	}
+	if GetEnumValueMap(p.Name) != nil {
+		return COMPARE_KIND_INT
+	}
+	switch p.GoType {
+	case "int", "int64", "int32", "int16", "int8", "uint", "uint64", "uint32", "uint16", "uint8":
+		return COMPARE_KIND_INT
+	case "float32", "float64":
+		return COMPARE_KIND_FLOAT
+	case "string":
+		return COMPARE_KIND_STRING
+	default:
+	}
+	return COMPARE_KIND_AUTO
+}
+
+// NormalizeValue 将 json 中的值或单元格中的字符串按类型转为 int64、float64 或 string，
+// 转换后相等的值即为相同的值，如整数列中的 1001、1001.0 与 "01001"，值不符合类型时返回错误
+func NormalizeValue(Type string, data interface{}) (interface{}, error) {
+	switch GetCompareKind(Type) {
+	case COMPARE_KIND_INT:
+		if s, isString := data.(string); isString {
+			if value, isEnumName := GetEnumValueMap(Type)[s]; isEnumName {
+				return value, nil
+			}
+		}
+		return normalizeInt(data)
+	case COMPARE_KIND_FLOAT:
+		return normalizeFloat(data)
+	case COMPARE_KIND_STRING:
+		return normalizeString(data), nil
+	default:
+	}
+	if i, err := normalizeInt(data); err == nil {
+		return i, nil
+	}
+	if f, err := normalizeFloat(data); err == nil {
+		return f, nil
+	}
+	return normalizeString(data), nil
+}
+
+// CellContent 将导出的单元格值转为单元格内容，空单元格导出的 nil 为空字符串
+func CellContent(data interface{}) string {
+	if data == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", data)
+}
+
+// CompareValue 按被引用列的类型比较 json 中的值与引用的内容，内容不符合类型时返回错误
+func CompareValue(Type string, data interface{}, content string) (bool, error) {
+	expect, err := NormalizeValue(Type, content)
+	if err != nil {
+		return false, fmt.Errorf("content '%v' is incompatible with type %v: %v", content, Type, err)
+	}
+	actual, err := NormalizeValue(Type, data)
+	if err != nil {
+		return false, fmt.Errorf("data '%v' is incompatible with type %v: %v", data, Type, err)
+	}
+	return actual == expect, nil
+}
+
+func normalizeInt(data interface{}) (int64, error) {
+	switch d := data.(type) {
+	case int64:
+		return d, nil
+	case int:
+		return int64(d), nil
+	case int32:
+		return int64(d), nil
+	case uint64:
+		if d <= math.MaxInt64 {
+			return int64(d), nil
+		}
+	case json.Number:
+		if i, err := d.Int64(); err == nil {
+			return i, nil
+		}
+		if f, err := d.Float64(); err == nil {
+			return floatToInt(f)
+		}
+	case float64:
+		return floatToInt(d)
+	case string:
+		// 与导出时整数列的解析相同，单元格中的 "010" 与导出的 10 相等
+		s := strings.TrimSpace(d)
+		if i, err := ParseIntText(s, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return floatToInt(f)
+		}
+	default:
+	}
+	return 0, fmt.Errorf("value '%v' is not an integer", data)
+}
+
+// floatToInt 只接受没有小数部分且在 int64 范围内的浮点数
+func floatToInt(f float64) (int64, error) {
+	if f != math.Trunc(f) || f < math.MinInt64 || f >= math.MaxInt64 {
+		return 0, fmt.Errorf("value %v is not an integer", strconv.FormatFloat(f, 'f', -1, 64))
+	}
+	return int64(f), nil
+}
+
+func normalizeFloat(data interface{}) (float64, error) {
+	switch d := data.(type) {
+	case float64:
+		return d, nil
+	case float32:
+		return float64(d), nil
+	case int64:
+		return float64(d), nil
+	case int:
+		return float64(d), nil
+	case json.Number:
+		return d.Float64()
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(d), 64); err == nil {
+			return f, nil
+		}
+	default:
+	}
+	return 0, fmt.Errorf("value '%v' is not a number", data)
+}
+
+// normalizeString 浮点数不使用科学计数法，避免 1e+06 与 1000000 不相等
+func normalizeString(data interface{}) string {
+	switch d := data.(type) {
+	case nil:
+		return ""
+	case string:
+		return d
+	case json.Number:
+		return d.String()
+	case float64:
+		return strconv.FormatFloat(d, 'f', -1, 64)
+	default:
+	}
+	return fmt.Sprintf("%v", data)
+}
@@ -0,0 +1,147 @@
+package utility
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetCompareKind(t *testing.T) {
+	if err := RegisterEnum("TestCompareQuality", map[string]int64{"WHITE": 1, "GREEN": 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		Type   string
+		expect string
+	}{
+		{"int", COMPARE_KIND_INT},
+		{"int32", COMPARE_KIND_INT},
+		{"uint64", COMPARE_KIND_INT},
+		{"TestCompareQuality", COMPARE_KIND_INT},
+		{"float", COMPARE_KIND_FLOAT},
+		{"double", COMPARE_KIND_FLOAT},
+		{"string", COMPARE_KIND_STRING},
+		{"[]int", COMPARE_KIND_AUTO},
+		{"", COMPARE_KIND_AUTO},
+		{"unknown", COMPARE_KIND_AUTO},
+	} {
+		if kind := GetCompareKind(c.Type); kind != c.expect {
+			t.Errorf("GetCompareKind(%q) = %q, expect %q", c.Type, kind, c.expect)
+		}
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	if err := RegisterEnum("TestCompareQuality", map[string]int64{"WHITE": 1, "GREEN": 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		Type    string
+		data    interface{}
+		expect  interface{}
+		isError bool
+	}{
+		{"int", int64(1001), int64(1001), false},
+		{"int", 1001.0, int64(1001), false},
+		{"int", "01001", int64(1001), false},
+		{"int", " 1001 ", int64(1001), false},
+		{"int", "010", int64(10), false},
+		{"int", "0x10", int64(16), false},
+		{"int", "1001.0", int64(1001), false},
+		{"int", json.Number("9007199254740993"), int64(9007199254740993), false},
+		{"int", uint64(1001), int64(1001), false},
+		{"int", 1001.5, nil, true},
+		{"int", "abc", nil, true},
+		{"int", nil, nil, true},
+		{"TestCompareQuality", "GREEN", int64(2), false},
+		{"TestCompareQuality", "2", int64(2), false},
+		{"float", "0.5", 0.5, false},
+		{"float", json.Number("1e6"), 1000000.0, false},
+		{"float", int64(2), 2.0, false},
+		{"float", "abc", nil, true},
+		{"string", "01001", "01001", false},
+		{"string", 1000000.0, "1000000", false},
+		{"string", json.Number("1001"), "1001", false},
+		{"string", nil, "", false},
+		{"", "1001", int64(1001), false},
+		{"", "0.5", 0.5, false},
+		{"", "abc", "abc", false},
+		{"", 1e6, int64(1000000), false},
+	} {
+		v, err := NormalizeValue(c.Type, c.data)
+		if c.isError != (err != nil) {
+			t.Errorf("NormalizeValue(%q, %#v) error = %v, expect error %v", c.Type, c.data, err, c.isError)
+			continue
+		}
+		if !c.isError && v != c.expect {
+			t.Errorf("NormalizeValue(%q, %#v) = %#v, expect %#v", c.Type, c.data, v, c.expect)
+		}
+	}
+}
+
+func TestCompareValue(t *testing.T) {
+	for _, c := range []struct {
+		Type    string
+		data    interface{}
+		content string
+		expect  bool
+		isError bool
+	}{
+		{"int", json.Number("1001"), "1001", true, false},
+		{"int", json.Number("1001"), "01001", true, false},
+		{"int", json.Number("1001"), "1001.0", true, false},
+		{"int", json.Number("1001"), "1002", false, false},
+		{"int", int64(10), "010", true, false},
+		{"int", int64(8), "010", false, false},
+		{"int", json.Number("9007199254740993"), "9007199254740993", true, false},
+		{"int", json.Number("9007199254740993"), "9007199254740992", false, false},
+		{"int", json.Number("1001"), "abc", false, true},
+		{"int", "abc", "1001", false, true},
+		{"float", json.Number("1e+06"), "1000000", true, false},
+		{"float", 0.1, "0.10", true, false},
+		{"string", "01001", "1001", false, false},
+		{"string", "a", "a", true, false},
+		{"", json.Number("1001"), "1001.0", true, false},
+		{"", "a", "a", true, false},
+		{"", nil, "", true, false},
+	} {
+		equal, err := CompareValue(c.Type, c.data, c.content)
+		if c.isError != (err != nil) {
+			t.Errorf("CompareValue(%q, %#v, %q) error = %v, expect error %v", c.Type, c.data, c.content, err, c.isError)
+			continue
+		}
+		if equal != c.expect {
+			t.Errorf("CompareValue(%q, %#v, %q) = %v, expect %v", c.Type, c.data, c.content, equal, c.expect)
+		}
+	}
+}
+
+func TestCellContent(t *testing.T) {
+	for _, c := range []struct {
+		data   interface{}
+		expect string
+	}{
+		{nil, ""},
+		{"", ""},
+		{"a", "a"},
+		{int64(0), "0"},
+		{json.Number("1001"), "1001"},
+	} {
+		if content := CellContent(c.data); content != c.expect {
+			t.Errorf("CellContent(%#v) = %q, expect %q", c.data, content, c.expect)
+		}
+	}
+}
+
+// TestCompareExportedInt 导出时解析出的整数与单元格原文比较时相等
+func TestCompareExportedInt(t *testing.T) {
+	for _, content := range []string{"1001", "01001", "010", "08", "09", "-07", "0x1F", "+5"} {
+		exported, err := ParseValue("int", content)
+		if err != nil {
+			t.Errorf("ParseValue(int, %q) error: %v", content, err)
+			continue
+		}
+		if equal, err := CompareValue("int", exported, content); err != nil || !equal {
+			t.Errorf("CompareValue(int, %v, %q) = %v, %v, expect equal", exported, content, equal, err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package utility
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+const (
+	ENCODING_AUTO = "auto"
+)
+
+// 自动检测时用于判断编码的长度，从第一个非 ASCII 字节开始计算
+const encodingDetectSize = 64 * 1024
+
+var (
+	utf8BOM    = []byte{0xEF, 0xBB, 0xBF}
+	utf16LEBOM = []byte{0xFF, 0xFE}
+	utf16BEBOM = []byte{0xFE, 0xFF}
+)
+
+var defaultEncodingName = ENCODING_AUTO
+
+// SetDefaultEncoding 设置 ConvertFileContentToJson 使用的编码，auto 表示自动检测
+func SetDefaultEncoding(encodingName string) error {
+	if _, err := GetEncoding(encodingName); err != nil {
+		return err
+	}
+	defaultEncodingName = encodingName
+	return nil
+}
+
+// GetDefaultEncoding 返回 SetDefaultEncoding 或项目配置中 Encoding 设置的编码名
+func GetDefaultEncoding() string {
+	return defaultEncodingName
+}
+
+// GetEncoding 返回编码名对应的编码，auto 返回 nil
+func GetEncoding(encodingName string) (encoding.Encoding, error) {
+	switch strings.ToLower(strings.TrimSpace(encodingName)) {
+	case "", ENCODING_AUTO:
+		return nil, nil
+	case "utf-8", "utf8":
+		return unicode.UTF8, nil
+	case "utf-8-bom", "utf8-bom":
+		return unicode.UTF8BOM, nil
+	case "utf-16", "utf16":
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM), nil
+	case "utf-16le", "utf16le":
+		return unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), nil
+	case "utf-16be", "utf16be":
+		return unicode.UTF16(unicode.BigEndian, unicode.UseBOM), nil
+	case "gbk":
+		return simplifiedchinese.GBK, nil
+	case "gb18030":
+		return simplifiedchinese.GB18030, nil
+	default:
+	}
+	return nil, fmt.Errorf("unknown encoding '%v'", encodingName)
+}
+
+// NewDecodeReader 将 r 解码为 UTF-8，encodingName 为空或 auto 时自动检测：
+// 带 BOM 的 UTF-8/UTF-16 按 BOM 解码，否则在第一个非 ASCII 字节处检测，合法的 UTF-8 原样读取，否则按 GB18030（兼容 GBK）解码
+func NewDecodeReader(r io.Reader, encodingName string) (io.Reader, error) {
+	e, err := GetEncoding(encodingName)
+	if err != nil {
+		return nil, err
+	}
+	if e != nil {
+		return transform.NewReader(r, e.NewDecoder()), nil
+	}
+
+	bufferReader := bufio.NewReaderSize(r, encodingDetectSize)
+	head, err := bufferReader.Peek(len(utf8BOM))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if e := detectBOMEncoding(head); e != nil {
+		return transform.NewReader(bufferReader, e.NewDecoder()), nil
+	}
+	return &autoDecodeReader{r: bufferReader}, nil
+}
+
+// autoDecodeReader 在第一个非 ASCII 字节之前原样输出（ASCII 在 UTF-8 与 GB18030 中相同），
+// 之后从该字节开始取 encodingDetectSize 字节检测编码，开头很长的 ASCII 表头不会影响检测
+type autoDecodeReader struct {
+	r       *bufio.Reader
+	decoder io.Reader
+}
+
+func (a *autoDecodeReader) Read(p []byte) (int, error) {
+	if a.decoder != nil {
+		return a.decoder.Read(p)
+	}
+	if len(p) == 0 {
+		return 0, nil
+	}
+	if a.r.Buffered() == 0 {
+		if _, err := a.r.Peek(1); err != nil {
+			return 0, err
+		}
+	}
+	size := a.r.Buffered()
+	if size > len(p) {
+		size = len(p)
+	}
+	buffered, _ := a.r.Peek(size)
+	asciiSize := 0
+	for asciiSize < len(buffered) && buffered[asciiSize] < utf8.RuneSelf {
+		asciiSize++
+	}
+	if asciiSize != 0 {
+		copy(p, buffered[:asciiSize])
+		a.r.Discard(asciiSize)
+		return asciiSize, nil
+	}
+
+	head, err := a.r.Peek(encodingDetectSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return 0, err
+	}
+	a.decoder = transform.NewReader(a.r, detectTextEncoding(head, err == nil).NewDecoder())
+	return a.decoder.Read(p)
+}
+
+// DetectEncoding 根据内容前缀检测编码，truncated 表示 head 不是完整内容，末尾可能截断了多字节字符
+func DetectEncoding(head []byte, truncated bool) encoding.Encoding {
+	if e := detectBOMEncoding(head); e != nil {
+		return e
+	}
+	return detectTextEncoding(head, truncated)
+}
+
+func detectBOMEncoding(head []byte) encoding.Encoding {
+	switch {
+	case bytes.HasPrefix(head, utf8BOM):
+		return unicode.UTF8BOM
+	case bytes.HasPrefix(head, utf16LEBOM):
+		return unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)
+	case bytes.HasPrefix(head, utf16BEBOM):
+		return unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)
+	default:
+	}
+	return nil
+}
+
+// detectTextEncoding 没有 BOM 的内容是合法的 UTF-8 时为 UTF-8，否则为 GB18030
+func detectTextEncoding(head []byte, truncated bool) encoding.Encoding {
+	if truncated {
+		head = trimIncompleteRune(head)
+	}
+	if utf8.Valid(head) {
+		return unicode.UTF8
+	}
+	return simplifiedchinese.GB18030
+}
+
+func trimIncompleteRune(b []byte) []byte {
+	for i := 1; i <= utf8.UTFMax && i <= len(b); i++ {
+		if utf8.RuneStart(b[len(b)-i]) {
+			if !utf8.FullRune(b[len(b)-i:]) {
+				return b[:len(b)-i]
+			}
+			break
+		}
+	}
+	return b
+}
@@ -0,0 +1,104 @@
+package utility
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+func encodeTestString(t *testing.T, e encoding.Encoding, s string) []byte {
+	b, err := e.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDetectEncoding(t *testing.T) {
+	content := "名字,等级\n宝剑,1\n"
+	for _, c := range []struct {
+		name      string
+		head      []byte
+		truncated bool
+		expect    encoding.Encoding
+	}{
+		{"utf-8", []byte(content), false, unicode.UTF8},
+		{"ascii", []byte("id,name\n"), false, unicode.UTF8},
+		{"utf-8 bom", append(append([]byte{}, utf8BOM...), content...), false, unicode.UTF8BOM},
+		{"utf-16le bom", []byte{0xFF, 0xFE, 'a', 0}, false, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)},
+		{"utf-16be bom", []byte{0xFE, 0xFF, 0, 'a'}, false, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)},
+		{"gbk", encodeTestString(t, simplifiedchinese.GBK, content), false, simplifiedchinese.GB18030},
+		// 截断在多字节字符中间的 UTF-8 仍然是 UTF-8
+		{"truncated utf-8", []byte(content)[:4], true, unicode.UTF8},
+		{"incomplete utf-8", []byte(content)[:4], false, simplifiedchinese.GB18030},
+	} {
+		if e := DetectEncoding(c.head, c.truncated); e != c.expect {
+			t.Errorf("%v: DetectEncoding = %v, expect %v", c.name, e, c.expect)
+		}
+	}
+}
+
+func TestNewDecodeReader(t *testing.T) {
+	content := "名字,等级\n宝剑,1\n"
+	for _, c := range []struct {
+		encodingName string
+		input        []byte
+	}{
+		{ENCODING_AUTO, []byte(content)},
+		{ENCODING_AUTO, append(append([]byte{}, utf8BOM...), content...)},
+		{ENCODING_AUTO, encodeTestString(t, simplifiedchinese.GBK, content)},
+		{ENCODING_AUTO, encodeTestString(t, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), content)},
+		{"gbk", encodeTestString(t, simplifiedchinese.GBK, content)},
+		{"utf-8", []byte(content)},
+	} {
+		r, err := NewDecodeReader(bytes.NewReader(c.input), c.encodingName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(output) != content {
+			t.Errorf("NewDecodeReader(%v, % x) = %q, expect %q", c.encodingName, c.input[:4], output, content)
+		}
+	}
+	if _, err := NewDecodeReader(bytes.NewReader(nil), "latin-9"); err == nil {
+		t.Errorf("NewDecodeReader with unknown encoding expect error")
+	}
+}
+
+// 开头的 ASCII 超过检测长度时，按第一个非 ASCII 字节之后的内容检测编码
+func TestNewDecodeReaderLongAsciiHead(t *testing.T) {
+	content := "id,name\n" + strings.Repeat("1001,sword\n", encodingDetectSize/8) + "1002,宝剑\n1003,盾牌\n"
+	for _, c := range []struct {
+		name  string
+		input []byte
+	}{
+		{"gbk", encodeTestString(t, simplifiedchinese.GBK, content)},
+		{"utf-8", []byte(content)},
+		{"ascii", []byte(strings.Repeat("1001,sword\n", encodingDetectSize/8))},
+	} {
+		r, err := NewDecodeReader(iotest.OneByteReader(bytes.NewReader(c.input)), ENCODING_AUTO)
+		if err != nil {
+			t.Fatal(err)
+		}
+		output, err := ioutil.ReadAll(iotest.HalfReader(r))
+		if err != nil {
+			t.Fatalf("%v: %v", c.name, err)
+		}
+		expect := content
+		if c.name == "ascii" {
+			expect = string(c.input)
+		}
+		if string(output) != expect {
+			t.Errorf("%v: NewDecodeReader output length %v tail %q, expect length %v tail %q", c.name, len(output), output[len(output)-20:], len(expect), expect[len(expect)-20:])
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package utility
+
+import (
+	"fmt"
+	"strings"
+)
+
+type HeaderRole int
+
+const (
+	HEADER_IGNORE HeaderRole = iota + 1
+	HEADER_COMMENT
+	HEADER_FORMATION
+	HEADER_OPS
+	HEADER_KEY
+	HEADER_TYPE
+	HEADER_DEFAULT
+	HEADER_DESCRIPTION
+)
+
+var headerRoleNameMap = map[string]HeaderRole{
+	"ignore":      HEADER_IGNORE,
+	"comment":     HEADER_COMMENT,
+	"formation":   HEADER_FORMATION,
+	"ops":         HEADER_OPS,
+	"key":         HEADER_KEY,
+	"type":        HEADER_TYPE,
+	"default":     HEADER_DEFAULT,
+	"description": HEADER_DESCRIPTION,
+}
+
+// HeaderLayout 描述表头每一行的用途，数据行从表头之后开始
+type HeaderLayout struct {
+	RoleSlice []HeaderRole
+}
+
+// DefaultHeaderLayout 注释行、策划注释行（formation）、ops 行、key 行、type 行
+var DefaultHeaderLayout = &HeaderLayout{
+	RoleSlice: []HeaderRole{HEADER_COMMENT, HEADER_FORMATION, HEADER_OPS, HEADER_KEY, HEADER_TYPE},
+}
+
+var defaultHeaderLayout = DefaultHeaderLayout
+
+// NewHeaderLayout 按行的用途名创建表头布局，例如 []string{"key", "type", "ops", "comment"}
+func NewHeaderLayout(roleNameSlice []string) (*HeaderLayout, error) {
+	layout := &HeaderLayout{}
+	for _, roleName := range roleNameSlice {
+		role, hasRole := headerRoleNameMap[strings.ToLower(strings.TrimSpace(roleName))]
+		if !hasRole {
+			return nil, fmt.Errorf("unknown header role '%v'", roleName)
+		}
+		layout.RoleSlice = append(layout.RoleSlice, role)
+	}
+	if err := layout.Validate(); err != nil {
+		return nil, err
+	}
+	return layout, nil
+}
+
+// Validate 检查布局必须包含 key 行，且除 ignore 外每种用途最多出现一次
+func (l *HeaderLayout) Validate() error {
+	roleCountMap := make(map[HeaderRole]int)
+	for _, role := range l.RoleSlice {
+		roleCountMap[role]++
+		if role != HEADER_IGNORE && roleCountMap[role] > 1 {
+			return fmt.Errorf("header role %v appears more than once", role.String())
+		}
+	}
+	if roleCountMap[HEADER_KEY] == 0 {
+		return fmt.Errorf("header layout does not have key row")
+	}
+	return nil
+}
+
+// GetDefaultHeaderLayout 返回 SetDefaultHeaderLayout 或项目配置中 Header 设置的表头布局
+func GetDefaultHeaderLayout() *HeaderLayout {
+	return defaultHeaderLayout
+}
+
+// SetDefaultHeaderLayout 设置 ProcessCsv 与 ProcessCsvAndFormation 使用的表头布局
+func SetDefaultHeaderLayout(layout *HeaderLayout) error {
+	if err := layout.Validate(); err != nil {
+		return err
+	}
+	defaultHeaderLayout = layout
+	return nil
+}
+
+func (r HeaderRole) String() string {
+	for name, role := range headerRoleNameMap {
+		if role == r {
+			return name
+		}
+	}
+	return fmt.Sprintf("HeaderRole(%d)", int(r))
+}
+
+// Header 按布局读取出的表头
+type Header struct {
+	RowMap map[HeaderRole][]string
+}
+
+func (l *HeaderLayout) ReadHeader(fileReader RowReader) (*Header, error) {
+	header := &Header{RowMap: make(map[HeaderRole][]string)}
+	for _, role := range l.RoleSlice {
+		row, err := fileReader.Read()
+		if err != nil {
+			return nil, fmt.Errorf("read header %v row occurs error: %v", role.String(), err)
+		}
+		if role != HEADER_IGNORE {
+			header.RowMap[role] = row
+		}
+	}
+	return header, nil
+}
+
+// Cell 返回指定用途行的第 index 列，布局中没有该行或者该行较短时返回空字符串
+func (h *Header) Cell(role HeaderRole, index int) string {
+	row := h.RowMap[role]
+	if index < 0 || index >= len(row) {
+		return ""
+	}
+	return row[index]
+}
+
+// Type 返回第 index 列的类型，布局中没有 type 行时所有列均视为 string
+func (h *Header) Type(index int) string {
+	if _, hasType := h.RowMap[HEADER_TYPE]; !hasType {
+		return "string"
+	}
+	return h.Cell(HEADER_TYPE, index)
+}
+
+func (h *Header) KeyRow() []string {
+	return h.RowMap[HEADER_KEY]
+}
@@ -0,0 +1,73 @@
+package utility
+
+import (
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewHeaderLayout(t *testing.T) {
+	for _, c := range []struct {
+		roleNameSlice []string
+		expect        []HeaderRole
+		isError       bool
+	}{
+		{[]string{"key", "type", "ops"}, []HeaderRole{HEADER_KEY, HEADER_TYPE, HEADER_OPS}, false},
+		{[]string{" Key ", "ignore", "ignore", "Comment"}, []HeaderRole{HEADER_KEY, HEADER_IGNORE, HEADER_IGNORE, HEADER_COMMENT}, false},
+		{[]string{"type", "ops"}, nil, true},
+		{[]string{"key", "key"}, nil, true},
+		{[]string{"key", "unknown"}, nil, true},
+	} {
+		layout, err := NewHeaderLayout(c.roleNameSlice)
+		if c.isError {
+			if err == nil {
+				t.Errorf("NewHeaderLayout(%q) expect error", c.roleNameSlice)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("NewHeaderLayout(%q) error: %v", c.roleNameSlice, err)
+			continue
+		}
+		if !reflect.DeepEqual(layout.RoleSlice, c.expect) {
+			t.Errorf("NewHeaderLayout(%q) = %v, expect %v", c.roleNameSlice, layout.RoleSlice, c.expect)
+		}
+	}
+}
+
+func TestReadHeader(t *testing.T) {
+	layout, err := NewHeaderLayout([]string{"key", "type", "ignore", "ops"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	csvReader := csv.NewReader(strings.NewReader("id,name\nint,string\nnote,note\nserver,client\n1,a\n"))
+	header, err := layout.ReadHeader(csvReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(header.KeyRow(), []string{"id", "name"}) {
+		t.Errorf("KeyRow() = %v", header.KeyRow())
+	}
+	if header.Type(1) != "string" || header.Cell(HEADER_OPS, 1) != "client" {
+		t.Errorf("Type(1) = %v, Cell(ops, 1) = %v", header.Type(1), header.Cell(HEADER_OPS, 1))
+	}
+	if header.Cell(HEADER_FORMATION, 0) != "" || header.Cell(HEADER_KEY, 5) != "" {
+		t.Errorf("Cell of missing row or column expect empty string")
+	}
+	if row, _ := csvReader.Read(); !reflect.DeepEqual(row, []string{"1", "a"}) {
+		t.Errorf("first data row = %v", row)
+	}
+
+	noTypeHeader, err := (&HeaderLayout{RoleSlice: []HeaderRole{HEADER_KEY}}).ReadHeader(csv.NewReader(strings.NewReader("id\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if noTypeHeader.Type(0) != "string" {
+		t.Errorf("Type(0) without type row = %v, expect string", noTypeHeader.Type(0))
+	}
+
+	if _, err := layout.ReadHeader(csv.NewReader(strings.NewReader("id\n"))); err == nil {
+		t.Errorf("ReadHeader with too few rows expect error")
+	}
+}
@@ -0,0 +1,336 @@
+package utility
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Table 内存中的配置表，Data 中的值已按类型行解析
+type Table struct {
+	Name         string
+	Target       ExportTarget
+	KeySlice     []*KeyIndex
+	Format       map[string]int
+	Data         [][]interface{}
+	FormationMap map[string]string
+}
+
+// Export 返回每个导出列的导出目标
+func (t *Table) Export() map[string][]string {
+	return keySliceExport(t.KeySlice)
+}
+
+// Types 返回每个导出列的类型
+func (t *Table) Types() map[string]string {
+	return keySliceTypes(t.KeySlice, t.Target)
+}
+
+// Keys 返回表的主键列名
+func (t *Table) Keys() []string {
+	return primaryKeyNames(t.Name, t.KeySlice, t.Target)
+}
+
+// primaryKeyNames 返回导出到 target 的主键列名，项目配置中声明的主键优先于 ops 行的主键标记
+func primaryKeyNames(tableName string, keySlice []*KeyIndex, target ExportTarget) []string {
+	declaredKeySlice := GetPrimaryKeys(tableName)
+	keyNameSlice := make([]string, 0)
+	for _, key := range keySlice {
+		if !key.Targets.Contains(target) {
+			continue
+		}
+		if len(declaredKeySlice) == 0 {
+			if key.PrimaryKey {
+				keyNameSlice = append(keyNameSlice, key.Name)
+			}
+			continue
+		}
+		for _, declaredKey := range declaredKeySlice {
+			if declaredKey == key.Name {
+				keyNameSlice = append(keyNameSlice, key.Name)
+				break
+			}
+		}
+	}
+	return keyNameSlice
+}
+
+// keySliceTypes 返回导出到 target 的列在类型行中声明的类型
+func keySliceTypes(keySlice []*KeyIndex, target ExportTarget) map[string]string {
+	types := make(map[string]string, len(keySlice))
+	for _, key := range keySlice {
+		if key.Targets.Contains(target) {
+			types[key.Name] = key.Type
+		}
+	}
+	return types
+}
+
+func keySliceExport(keySlice []*KeyIndex) map[string][]string {
+	export := make(map[string][]string, len(keySlice))
+	for _, key := range keySlice {
+		export[key.Name] = key.Targets.Slice()
+	}
+	return export
+}
+
+// WriteJSON 以 Format/Data/Types 的形式输出，EXPORT_ALL 的表额外输出 Export
+func (t *Table) WriteJSON(w io.Writer) error {
+	jsonWriter, err := newTableJsonWriter(w, t.Format)
+	if err != nil {
+		return err
+	}
+	for _, row := range t.Data {
+		if err := jsonWriter.WriteRow(row); err != nil {
+			return err
+		}
+	}
+	if t.Target == EXPORT_ALL {
+		return jsonWriter.Close(t.Export(), t.Keys(), t.Types())
+	}
+	return jsonWriter.Close(nil, t.Keys(), t.Types())
+}
+
+// TableLoader 流式读取一张配置表：创建时读取表头，之后每次 Next 解析一行数据
+type TableLoader struct {
+	Name         string
+	Target       ExportTarget
+	Header       *Header
+	KeyMap       map[int]*KeyIndex
+	KeySlice     []*KeyIndex
+	Format       map[string]int
+	FormationMap map[string]string
+	// Strict 为 true（NewTableLoader 的默认值）时，不符合类型的单元格与重复的主键会使 Next 返回错误，
+	// Load 与 WriteJSON 边读取边执行注册的 TableChecker；为 false 时按类型零值导出，不检查主键与整张表
+	Strict bool
+
+	fileReader RowReader
+	headerSize int
+	rowCount   int
+	// keyValueRowMap 主键值到其所在的行，Strict 时用于检查主键唯一
+	keyValueRowMap map[string]int
+}
+
+// NewTableLoader 读取表头，name 为表名（文件名去掉扩展名或 sheet 名），用于查找项目配置中声明的主键
+func NewTableLoader(name string, fileReader RowReader, layout *HeaderLayout, target ExportTarget) (*TableLoader, error) {
+	header, err := layout.ReadHeader(fileReader)
+	if err != nil {
+		return nil, err
+	}
+	keyMap, format, formationMap := BuildKeyIndex(header, target)
+	loader := &TableLoader{
+		Name:         name,
+		Target:       target,
+		Header:       header,
+		KeyMap:       keyMap,
+		Format:       format,
+		FormationMap: formationMap,
+		Strict:       true,
+		fileReader:   fileReader,
+		headerSize:   len(layout.RoleSlice),
+	}
+	for _, key := range keyMap {
+		loader.KeySlice = append(loader.KeySlice, key)
+	}
+	sort.Slice(loader.KeySlice, func(i, j int) bool {
+		return loader.KeySlice[i].Index < loader.KeySlice[j].Index
+	})
+	return loader, nil
+}
+
+// Next 返回下一行解析后的数据，跳过没有任何单元格的空行，读取结束时返回 io.EOF
+func (l *TableLoader) Next() ([]interface{}, error) {
+	line, err := l.fileReader.Read()
+	for err == nil && len(line) == 0 {
+		l.rowCount++
+		line, err = l.fileReader.Read()
+	}
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	if err != nil && line == nil {
+		return nil, err
+	}
+	l.rowCount++
+	if l.Strict {
+		if validateErrorSlice := ValidateLine(line, l.KeyMap); len(validateErrorSlice) != 0 {
+			return nil, fmt.Errorf("row %v: %v", l.headerSize+l.rowCount, validateErrorSlice[0])
+		}
+	}
+	row := ProcessLine(line, l.KeyMap)
+	if l.Strict {
+		if err := l.checkPrimaryKey(row); err != nil {
+			return nil, err
+		}
+	}
+	return row, nil
+}
+
+// checkPrimaryKey 检查本行的主键（或联合主键）没有在之前的行中出现
+func (l *TableLoader) checkPrimaryKey(row []interface{}) error {
+	keyNameSlice := primaryKeyNames(l.Name, l.KeySlice, l.Target)
+	if len(keyNameSlice) == 0 {
+		return nil
+	}
+	valueSlice := make([]string, 0, len(keyNameSlice))
+	for _, keyName := range keyNameSlice {
+		index, hasKey := l.Format[keyName]
+		if !hasKey || index >= len(row) {
+			return nil
+		}
+		valueSlice = append(valueSlice, CellContent(row[index]))
+	}
+	keyValue := strings.Join(valueSlice, ",")
+	if l.keyValueRowMap == nil {
+		l.keyValueRowMap = make(map[string]int)
+	}
+	rowNumber := l.headerSize + l.rowCount
+	if lastRowNumber, hasKeyValue := l.keyValueRowMap[keyValue]; hasKeyValue {
+		return fmt.Errorf("row %v: primary key %v %v is duplicated with row %v", rowNumber, keyNameSlice, keyValue, lastRowNumber)
+	}
+	l.keyValueRowMap[keyValue] = rowNumber
+	return nil
+}
+
+// Load 读取剩余所有行，返回内存中的配置表
+func (l *TableLoader) Load() (*Table, error) {
+	table := l.headerTable()
+	table.Data = make([][]interface{}, 0, 128)
+	if err := l.forEachRow(func(row []interface{}) error {
+		table.Data = append(table.Data, row)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// headerTable 返回只有表头信息的 Table
+func (l *TableLoader) headerTable() *Table {
+	return &Table{
+		Name:         l.Name,
+		Target:       l.Target,
+		KeySlice:     l.KeySlice,
+		Format:       l.Format,
+		FormationMap: l.FormationMap,
+	}
+}
+
+// forEachRow 边读取边处理剩余的每一行，Strict 时同时逐行执行 TableChecker，读取结束后返回检查的错误
+func (l *TableLoader) forEachRow(handle func(row []interface{}) error) error {
+	var check *tableCheck
+	if l.Strict {
+		check = newTableCheck(l.headerTable())
+	}
+	for {
+		row, err := l.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if check != nil {
+			check.CheckRow(row)
+		}
+		if err := handle(row); err != nil {
+			return err
+		}
+	}
+	if check != nil {
+		return check.Close()
+	}
+	return nil
+}
+
+// WriteJSON 边读取边输出剩余所有行，不在内存中保留整张表；TableChecker 的错误在所有行输出之后返回，
+// 返回错误时 w 中的 json 不完整，缺少结尾的 Keys 与 Types
+func (l *TableLoader) WriteJSON(w io.Writer) error {
+	jsonWriter, err := newTableJsonWriter(w, l.Format)
+	if err != nil {
+		return err
+	}
+	if err := l.forEachRow(jsonWriter.WriteRow); err != nil {
+		return err
+	}
+	keyNameSlice := primaryKeyNames(l.Name, l.KeySlice, l.Target)
+	if l.Target == EXPORT_ALL {
+		return jsonWriter.Close(keySliceExport(l.KeySlice), keyNameSlice, keySliceTypes(l.KeySlice, l.Target))
+	}
+	return jsonWriter.Close(nil, keyNameSlice, keySliceTypes(l.KeySlice, l.Target))
+}
+
+// Project 将 EXPORT_ALL 加载出的一行数据投影为导出到 target 的列
+func (l *TableLoader) Project(row []interface{}, target ExportTarget) []interface{} {
+	r := make([]interface{}, 0, len(row))
+	for _, key := range l.KeySlice {
+		if key.Targets.Contains(target) && key.Index < len(row) {
+			r = append(r, row[key.Index])
+		}
+	}
+	return r
+}
+
+// tableJsonWriter 增量输出 {"Format":...,"Data":[...],"Export":...,"Keys":[...],"Types":...}
+type tableJsonWriter struct {
+	w        *bufio.Writer
+	rowCount int
+}
+
+func newTableJsonWriter(w io.Writer, format map[string]int) (*tableJsonWriter, error) {
+	jsonWriter := &tableJsonWriter{w: bufio.NewWriter(w)}
+	formatJson, err := json.Marshal(format)
+	if err != nil {
+		return nil, err
+	}
+	jsonWriter.w.WriteString(`{"Format":`)
+	jsonWriter.w.Write(formatJson)
+	_, err = jsonWriter.w.WriteString(`,"Data":[`)
+	return jsonWriter, err
+}
+
+func (jw *tableJsonWriter) WriteRow(row []interface{}) error {
+	rowJson, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+	if jw.rowCount != 0 {
+		jw.w.WriteByte(',')
+	}
+	jw.rowCount++
+	_, err = jw.w.Write(rowJson)
+	return err
+}
+
+func (jw *tableJsonWriter) Close(export map[string][]string, keyNameSlice []string, types map[string]string) error {
+	jw.w.WriteByte(']')
+	if export != nil {
+		exportJson, err := json.Marshal(export)
+		if err != nil {
+			return err
+		}
+		jw.w.WriteString(`,"Export":`)
+		jw.w.Write(exportJson)
+	}
+	if len(keyNameSlice) != 0 {
+		keysJson, err := json.Marshal(keyNameSlice)
+		if err != nil {
+			return err
+		}
+		jw.w.WriteString(`,"Keys":`)
+		jw.w.Write(keysJson)
+	}
+	if len(types) != 0 {
+		typesJson, err := json.Marshal(types)
+		if err != nil {
+			return err
+		}
+		jw.w.WriteString(`,"Types":`)
+		jw.w.Write(typesJson)
+	}
+	jw.w.WriteByte('}')
+	return jw.w.Flush()
+}
@@ -0,0 +1,227 @@
+package utility
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testLoaderCsv = "comment,comment,comment\n,,\nserver,server,client\npool_id,item_id,weight\nint,int,int\n1,1001,10\n1,1002,20\n"
+
+func testJsonKeys(t *testing.T, jsonString string) []string {
+	o := &struct {
+		Keys []string `json:"Keys"`
+	}{}
+	if err := json.Unmarshal([]byte(jsonString), o); err != nil {
+		t.Fatalf("json %v: %v", jsonString, err)
+	}
+	return o.Keys
+}
+
+func TestNewTableLoaderName(t *testing.T) {
+	loader, err := NewTableLoader("TestLoaderName", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Name != "TestLoaderName" {
+		t.Errorf("Table.Name = %q, expect TestLoaderName", table.Name)
+	}
+	expect := [][]interface{}{{int64(1), int64(1001)}, {int64(1), int64(1002)}}
+	if !reflect.DeepEqual(table.Data, expect) {
+		t.Errorf("Data = %v, expect %v", table.Data, expect)
+	}
+}
+
+func TestProjectPrimaryKeys(t *testing.T) {
+	SetPrimaryKeys("TestPool", []string{"pool_id", "item_id"})
+	defer delete(primaryKeyMap, "TestPool")
+
+	err, jsonString, _ := ProcessCsvAndFormationWithName("TestPool", csv.NewReader(strings.NewReader(testLoaderCsv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonString); !reflect.DeepEqual(keys, []string{"pool_id", "item_id"}) {
+		t.Errorf("ProcessCsvAndFormationWithName Keys = %v", keys)
+	}
+	// 不带表名的接口保持原来的签名，不使用项目配置中的主键
+	err, jsonString, _ = ProcessCsvAndFormation(csv.NewReader(strings.NewReader(testLoaderCsv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonString); len(keys) != 0 {
+		t.Errorf("ProcessCsvAndFormation Keys = %v, expect none", keys)
+	}
+	jsonString, _, err = ConvertFileContentToJson(strings.NewReader(testLoaderCsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonString); len(keys) != 0 {
+		t.Errorf("ConvertFileContentToJson Keys = %v, expect none", keys)
+	}
+	jsonString, _, err = ConvertFileContentToJsonWithName("TestPool", strings.NewReader(testLoaderCsv))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonString); !reflect.DeepEqual(keys, []string{"pool_id", "item_id"}) {
+		t.Errorf("ConvertFileContentToJsonWithName Keys = %v", keys)
+	}
+
+	jsonStringMap, _, err := ProcessCsvAndFormationForTargetsWithName("TestPool", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout, []ExportTarget{EXPORT_SERVER, EXPORT_CLIENT})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonStringMap[EXPORT_SERVER]); !reflect.DeepEqual(keys, []string{"pool_id", "item_id"}) {
+		t.Errorf("ProcessCsvAndFormationForTargetsWithName server Keys = %v", keys)
+	}
+	// 主键没有导出到客户端
+	if keys := testJsonKeys(t, jsonStringMap[EXPORT_CLIENT]); len(keys) != 0 {
+		t.Errorf("ProcessCsvAndFormationForTargetsWithName client Keys = %v, expect none", keys)
+	}
+}
+
+func TestConvertXlsxToJsonPrimaryKeys(t *testing.T) {
+	SetPrimaryKeys("Item", []string{"id"})
+	defer delete(primaryKeyMap, "Item")
+
+	path := filepath.Join(t.TempDir(), "item.xlsx")
+	if err := ioutil.WriteFile(path, newTestXlsxContent(t, testXlsxSheetXml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsonStringMap, _, err := ConvertXlsxToJson(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys := testJsonKeys(t, jsonStringMap["Item"]); !reflect.DeepEqual(keys, []string{"id"}) {
+		t.Errorf("ConvertXlsxToJson Keys = %v", keys)
+	}
+}
+
+func TestTableLoaderStrict(t *testing.T) {
+	invalidCsv := strings.Replace(testLoaderCsv, "1,1002,20", "1,abc,20", 1)
+	if err, _ := ProcessCsvWithName("TestStrict", csv.NewReader(strings.NewReader(invalidCsv))); err == nil {
+		t.Errorf("ProcessCsv with invalid int cell expect error")
+	}
+
+	loader, err := NewTableLoader("TestStrict", csv.NewReader(strings.NewReader(invalidCsv)), DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loader.Strict = false
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Data[1][1] != int64(0) {
+		t.Errorf("non-strict invalid cell = %v, expect 0", table.Data[1][1])
+	}
+}
+
+func TestTableLoaderDuplicatePrimaryKey(t *testing.T) {
+	SetPrimaryKeys("TestDuplicate", []string{"pool_id"})
+	defer delete(primaryKeyMap, "TestDuplicate")
+
+	err, _ := ProcessCsvWithName("TestDuplicate", csv.NewReader(strings.NewReader(testLoaderCsv)))
+	if err == nil || !strings.Contains(err.Error(), "duplicated with row 6") {
+		t.Errorf("ProcessCsv with duplicated primary key error = %v", err)
+	}
+}
+
+func TestProcessCsvEmptyCell(t *testing.T) {
+	emptyCellCsv := strings.Replace(testLoaderCsv, "1,1002,20", "1,,20", 1)
+	err, jsonString := ProcessCsvWithName("TestEmptyCell", csv.NewReader(strings.NewReader(emptyCellCsv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(jsonString, "[1,null]") {
+		t.Errorf("ProcessCsv empty int cell = %v, expect null", jsonString)
+	}
+}
+
+func TestProjectNullValues(t *testing.T) {
+	defer SetDefaultNullValues(LEGACY_NULL_VALUES)
+	for _, c := range []struct {
+		projectJson string
+		expect      []string
+	}{
+		{`{}`, LEGACY_NULL_VALUES},
+		{`{"NullValues":["","0","-1"]}`, []string{"", "0", "-1"}},
+		{`{"NullValues":[]}`, []string{}},
+	} {
+		SetDefaultNullValues(LEGACY_NULL_VALUES)
+		project, err := LoadProject(strings.NewReader(c.projectJson))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := project.Apply(); err != nil {
+			t.Fatal(err)
+		}
+		if nullValueSlice := GetDefaultNullValues(); !reflect.DeepEqual(nullValueSlice, c.expect) {
+			t.Errorf("%v GetDefaultNullValues = %#v, expect %#v", c.projectJson, nullValueSlice, c.expect)
+		}
+	}
+}
+
+func TestTableLoaderLeadingZeroId(t *testing.T) {
+	leadingZeroCsv := "comment,comment\n,\nserver,server\nid,weight\nint,int\n08,1\n09,2\n010,3\n"
+	loader, err := NewTableLoader("TestLeadingZero", csv.NewReader(strings.NewReader(leadingZeroCsv)), DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]interface{}{{int64(8), int64(1)}, {int64(9), int64(2)}, {int64(10), int64(3)}}
+	if !reflect.DeepEqual(table.Data, expect) {
+		t.Errorf("Data = %v, expect %v", table.Data, expect)
+	}
+}
+
+func TestProcessCsvToWriter(t *testing.T) {
+	err, expect, expectFormationMap := ProcessCsvAndFormationWithName("Pool", csv.NewReader(strings.NewReader(testLoaderCsv)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	builder := &strings.Builder{}
+	formationMap, err := ProcessCsvAndFormationToWriter(builder, "Pool", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout)
+	if err != nil || builder.String() != expect || !reflect.DeepEqual(formationMap, expectFormationMap) {
+		t.Errorf("ProcessCsvAndFormationToWriter = %q, %v, %v, expect %q, %v", builder.String(), formationMap, err, expect, expectFormationMap)
+	}
+
+	builder.Reset()
+	if _, err := ConvertFileContentToJsonWriter(builder, "Pool", strings.NewReader(testLoaderCsv), "utf-8"); err != nil || builder.String() != expect {
+		t.Errorf("ConvertFileContentToJsonWriter = %q, %v, expect %q", builder.String(), err, expect)
+	}
+
+	targetSlice := []ExportTarget{EXPORT_ALL, EXPORT_SERVER, EXPORT_CLIENT}
+	jsonStringMap, _, err := ProcessCsvAndFormationForTargetsWithName("Pool", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout, targetSlice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	builderMap := make(map[ExportTarget]*strings.Builder)
+	writerMap := make(map[ExportTarget]io.Writer)
+	for _, target := range targetSlice {
+		builderMap[target] = &strings.Builder{}
+		writerMap[target] = builderMap[target]
+	}
+	if _, err := ProcessCsvAndFormationForTargetsToWriter(writerMap, "Pool", csv.NewReader(strings.NewReader(testLoaderCsv)), DefaultHeaderLayout); err != nil {
+		t.Fatal(err)
+	}
+	for _, target := range targetSlice {
+		if builderMap[target].String() != jsonStringMap[target] {
+			t.Errorf("ProcessCsvAndFormationForTargetsToWriter %v = %q, expect %q", target, builderMap[target].String(), jsonStringMap[target])
+		}
+	}
+	if jsonStringMap[EXPORT_SERVER] != expect {
+		t.Errorf("server json = %q, expect %q", jsonStringMap[EXPORT_SERVER], expect)
+	}
+}
@@ -0,0 +1,99 @@
+package utility
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Project 项目配置文件，声明与具体配置表无关的全局设置，PrimaryKeys 为表名到主键列名，优先于 ops 行中的 key 标记，
+// NullValues 为没有声明 nullable 的 formation 中表示没有引用的值，不配置时为 LEGACY_NULL_VALUES，配置为 [] 时开启严格模式，
+// TimeZone 为 datetime 列的时区，取 IANA 时区名，例如 Asia/Shanghai，默认为 UTC
+type Project struct {
+	Encoding    string                      `json:"Encoding"`
+	Header      []string                    `json:"Header"`
+	PrimaryKeys map[string][]string         `json:"PrimaryKeys"`
+	Enums       map[string]map[string]int64 `json:"Enums"`
+	NullValues  []string                    `json:"NullValues"`
+	TimeZone    string                      `json:"TimeZone"`
+}
+
+func LoadProject(r io.Reader) (*Project, error) {
+	project := &Project{}
+	if err := json.NewDecoder(r).Decode(project); err != nil {
+		return nil, err
+	}
+	return project, nil
+}
+
+func LoadProjectFile(path string) (*Project, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadProject(f)
+}
+
+// Apply 将项目配置注册到全局，例如 CSV 编码、表头布局、主键以及把枚举注册为类型行可用的类型
+func (p *Project) Apply() error {
+	if len(p.Encoding) != 0 {
+		if err := SetDefaultEncoding(p.Encoding); err != nil {
+			return err
+		}
+	}
+	if len(p.Header) != 0 {
+		layout, err := NewHeaderLayout(p.Header)
+		if err != nil {
+			return err
+		}
+		defaultHeaderLayout = layout
+	}
+	for tableName, keyNameSlice := range p.PrimaryKeys {
+		SetPrimaryKeys(tableName, keyNameSlice)
+	}
+	for name, valueMap := range p.Enums {
+		if err := RegisterEnum(name, valueMap); err != nil {
+			return err
+		}
+	}
+	if p.NullValues != nil {
+		SetDefaultNullValues(p.NullValues)
+	}
+	if len(p.TimeZone) != 0 {
+		location, err := time.LoadLocation(p.TimeZone)
+		if err != nil {
+			return fmt.Errorf("project time zone %v is invalid: %v", p.TimeZone, err)
+		}
+		SetDefaultLocation(location)
+	}
+	return nil
+}
+
+var primaryKeyMap = make(map[string][]string)
+
+// SetPrimaryKeys 为表声明主键，多个列组成联合主键
+func SetPrimaryKeys(tableName string, keyNameSlice []string) {
+	primaryKeyMap[tableName] = append([]string{}, keyNameSlice...)
+}
+
+func GetPrimaryKeys(tableName string) []string {
+	return primaryKeyMap[tableName]
+}
+
+// LEGACY_NULL_VALUES 默认表示没有引用的值，与原来一样跳过空单元格、0 与 -1，已有的配置表不需要修改
+var LEGACY_NULL_VALUES = []string{"", "0", "-1"}
+
+var defaultNullValueSlice = LEGACY_NULL_VALUES
+
+// SetDefaultNullValues 设置没有声明 ? 与 nullable(...) 的 formation 中表示没有引用的值，默认为 LEGACY_NULL_VALUES，
+// 为空时开启严格模式，这些单元格不能为空，0 与 -1 也要能找到引用的值
+func SetDefaultNullValues(nullValueSlice []string) {
+	defaultNullValueSlice = append([]string{}, nullValueSlice...)
+}
+
+func GetDefaultNullValues() []string {
+	return defaultNullValueSlice
+}
@@ -0,0 +1,44 @@
+package utility
+
+import (
+	"fmt"
+	"io"
+)
+
+// Reporter 接收 utility 与 formation 在解析和检查过程中的诊断信息，这些信息同时以 error 返回，
+// 包本身不直接输出，默认的 Reporter 丢弃所有信息
+type Reporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+type discardReporter struct{}
+
+func (discardReporter) Errorf(format string, args ...interface{}) {}
+
+// writerReporter 每条信息输出一行，以 Error: 开头
+type writerReporter struct {
+	w io.Writer
+}
+
+func (r *writerReporter) Errorf(format string, args ...interface{}) {
+	fmt.Fprintf(r.w, "Error: "+format+"\n", args...)
+}
+
+// NewWriterReporter 返回输出到 w 的 Reporter，如 NewWriterReporter(os.Stderr)
+func NewWriterReporter(w io.Writer) Reporter {
+	return &writerReporter{w: w}
+}
+
+var reporter Reporter = discardReporter{}
+
+// SetReporter 设置包内使用的 Reporter，r 为 nil 时恢复为丢弃所有信息
+func SetReporter(r Reporter) {
+	if r == nil {
+		r = discardReporter{}
+	}
+	reporter = r
+}
+
+func GetReporter() Reporter {
+	return reporter
+}
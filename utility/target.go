@@ -0,0 +1,111 @@
+package utility
+
+import (
+	"sort"
+	"strings"
+)
+
+type ExportTarget string
+
+const (
+	EXPORT_SERVER ExportTarget = "server"
+	EXPORT_CLIENT ExportTarget = "client"
+	EXPORT_TOOLS  ExportTarget = "tools"
+	// EXPORT_ALL 不是真正的导出目标，表示导出所有至少属于一个目标的列，并在 json 中附带每列的导出目标
+	EXPORT_ALL ExportTarget = "all"
+)
+
+var exportTargetSlice = []ExportTarget{EXPORT_SERVER, EXPORT_CLIENT, EXPORT_TOOLS}
+
+// ops 行中表示多个导出目标的别名
+var exportTargetAliasMap = map[string][]ExportTarget{
+	"both": {EXPORT_SERVER, EXPORT_CLIENT},
+}
+
+// RegisterExportTarget 注册项目自定义的导出目标，注册后可以在 ops 行中使用
+func RegisterExportTarget(target ExportTarget) {
+	for _, t := range exportTargetSlice {
+		if t == target {
+			return
+		}
+	}
+	exportTargetSlice = append(exportTargetSlice, target)
+}
+
+func GetExportTargetSlice() []ExportTarget {
+	return append([]ExportTarget{}, exportTargetSlice...)
+}
+
+type ExportTargetSet map[ExportTarget]bool
+
+// ParseExportTargetSet 将 ops 单元格解析为导出目标集合，多个目标以 | , ; + 或空白分隔：
+// none 表示不导出，all 表示导出到所有目标，没有任何目标名（包括空单元格）时同样导出到所有目标
+func ParseExportTargetSet(ops string) ExportTargetSet {
+	set := make(ExportTargetSet)
+	hasTarget := false
+	for _, token := range strings.FieldsFunc(strings.ToLower(ops), isOpsSeparator) {
+		switch {
+		case token == "none":
+			hasTarget = true
+		case token == string(EXPORT_ALL):
+			hasTarget = true
+			for _, target := range exportTargetSlice {
+				set[target] = true
+			}
+		case len(exportTargetAliasMap[token]) != 0:
+			hasTarget = true
+			for _, target := range exportTargetAliasMap[token] {
+				set[target] = true
+			}
+		default:
+			for _, target := range exportTargetSlice {
+				if string(target) == token {
+					hasTarget = true
+					set[target] = true
+				}
+			}
+		}
+	}
+	if !hasTarget {
+		for _, target := range exportTargetSlice {
+			set[target] = true
+		}
+	}
+	return set
+}
+
+func isOpsSeparator(r rune) bool {
+	switch r {
+	case '|', ',', ';', '+', ' ', '\t':
+		return true
+	default:
+	}
+	return false
+}
+
+// Contains 判断集合是否导出到 target，EXPORT_ALL 表示导出到任意一个目标
+func (s ExportTargetSet) Contains(target ExportTarget) bool {
+	if target == EXPORT_ALL {
+		return len(s) != 0
+	}
+	return s[target]
+}
+
+func (s ExportTargetSet) Slice() []string {
+	targetSlice := make([]string, 0, len(s))
+	for target := range s {
+		targetSlice = append(targetSlice, string(target))
+	}
+	sort.Strings(targetSlice)
+	return targetSlice
+}
+
+// IsPrimaryKeyOps 判断 ops 单元格是否声明了主键，例如 server|key，主键标记不影响导出目标
+func IsPrimaryKeyOps(ops string) bool {
+	for _, token := range strings.FieldsFunc(strings.ToLower(ops), isOpsSeparator) {
+		if token == "key" || token == "pk" {
+			return true
+		}
+	}
+	return false
+}
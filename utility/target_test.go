@@ -0,0 +1,57 @@
+package utility
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseExportTargetSet(t *testing.T) {
+	for _, c := range []struct {
+		ops    string
+		expect []string
+	}{
+		{"", []string{"client", "server", "tools"}},
+		{"server", []string{"server"}},
+		{"Client", []string{"client"}},
+		{"both", []string{"client", "server"}},
+		{"all", []string{"client", "server", "tools"}},
+		{"none", []string{}},
+		{"NONE", []string{}},
+		{"client,tools", []string{"client", "tools"}},
+		{"server | client", []string{"client", "server"}},
+		{"server;tools+client", []string{"client", "server", "tools"}},
+		// 重复的目标只出现一次
+		{"server|server", []string{"server"}},
+		{"both|server", []string{"client", "server"}},
+		{"none|server", []string{"server"}},
+		// 不认识的名字被忽略，只有不认识的名字时与空单元格相同
+		{"sever", []string{"client", "server", "tools"}},
+		{"sever|client", []string{"client"}},
+		{"key", []string{"client", "server", "tools"}},
+		{"server|key", []string{"server"}},
+	} {
+		set := ParseExportTargetSet(c.ops)
+		if !reflect.DeepEqual(set.Slice(), c.expect) {
+			t.Errorf("ParseExportTargetSet(%q) = %v, expect %v", c.ops, set.Slice(), c.expect)
+		}
+	}
+}
+
+func TestExportTargetSetContains(t *testing.T) {
+	for _, c := range []struct {
+		ops    string
+		target ExportTarget
+		expect bool
+	}{
+		{"server", EXPORT_SERVER, true},
+		{"server", EXPORT_CLIENT, false},
+		{"server", EXPORT_ALL, true},
+		{"none", EXPORT_ALL, false},
+		{"none", EXPORT_SERVER, false},
+		{"both", EXPORT_TOOLS, false},
+	} {
+		if contains := ParseExportTargetSet(c.ops).Contains(c.target); contains != c.expect {
+			t.Errorf("ParseExportTargetSet(%q).Contains(%v) = %v, expect %v", c.ops, c.target, contains, c.expect)
+		}
+	}
+}
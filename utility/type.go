@@ -0,0 +1,318 @@
+package utility
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TypeParser 类型行中一个类型名对应的解析器，Parse 对空字符串返回该类型的零值，
+// GoType 为代码生成时使用的 Go 类型，为空时生成 interface{}
+type TypeParser struct {
+	Name   string
+	GoType string
+	Parse  func(string) (interface{}, error)
+}
+
+var typeParserMap map[string]*TypeParser
+var enumValueMapMap map[string]map[string]int64
+
+var datetimeLayoutSlice = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	time.RFC3339,
+}
+
+func init() {
+	typeParserMap = make(map[string]*TypeParser)
+	enumValueMapMap = make(map[string]map[string]int64)
+
+	for name, bitSize := range map[string]int{"int": 64, "int64": 64, "int32": 32, "int16": 16, "int8": 8} {
+		RegisterTypeParser(&TypeParser{Name: name, GoType: name, Parse: newIntParser(bitSize)})
+	}
+	for name, bitSize := range map[string]int{"uint": 64, "uint64": 64, "uint32": 32, "uint16": 16, "uint8": 8} {
+		RegisterTypeParser(&TypeParser{Name: name, GoType: name, Parse: newUintParser(bitSize)})
+	}
+	for name, bitSize := range map[string]int{"float": 32, "float32": 32, "double": 64, "float64": 64} {
+		RegisterTypeParser(&TypeParser{Name: name, GoType: fmt.Sprintf("float%v", bitSize), Parse: newFloatParser(bitSize)})
+	}
+	for _, name := range []string{"[]int", "[]int64", "[]int32"} {
+		RegisterTypeParser(&TypeParser{Name: name, GoType: name, Parse: parseIntSlice})
+	}
+
+	RegisterTypeParser(&TypeParser{Name: "string", GoType: "string", Parse: func(v string) (interface{}, error) { return v, nil }})
+	RegisterTypeParser(&TypeParser{Name: "bool", GoType: "bool", Parse: parseBool})
+	RegisterTypeParser(&TypeParser{Name: "[]string", GoType: "[]string", Parse: parseStringSlice})
+	RegisterTypeParser(&TypeParser{Name: "map<int,int>", GoType: "map[int]int", Parse: parseIntIntMap})
+	RegisterTypeParser(&TypeParser{Name: "datetime", GoType: "time.Time", Parse: parseDatetime})
+	RegisterTypeParser(&TypeParser{Name: "duration", GoType: "time.Duration", Parse: parseDuration})
+}
+
+// RegisterTypeParser 注册类型解析器，同名类型会被覆盖
+func RegisterTypeParser(p *TypeParser) {
+	typeParserMap[normalizeTypeName(p.Name)] = p
+}
+
+func GetTypeParser(Type string) *TypeParser {
+	return typeParserMap[normalizeTypeName(Type)]
+}
+
+// RegisterEnum 注册命名枚举，单元格可以填写枚举名或者枚举值，解析结果为 int64
+func RegisterEnum(name string, valueMap map[string]int64) error {
+	if p := GetTypeParser(name); p != nil && GetEnumValueMap(p.Name) == nil {
+		return fmt.Errorf("enum %v conflicts with built-in type", name)
+	}
+	enumValueMap := make(map[string]int64, len(valueMap))
+	enumValueSet := make(map[int64]bool, len(valueMap))
+	for k, v := range valueMap {
+		enumValueMap[k] = v
+		enumValueSet[v] = true
+	}
+	RegisterTypeParser(&TypeParser{
+		Name:   name,
+		GoType: name,
+		Parse: func(v string) (interface{}, error) {
+			if len(v) == 0 {
+				return int64(0), nil
+			}
+			if value, has := enumValueMap[v]; has {
+				return value, nil
+			}
+			value, err := ParseIntText(v, 64)
+			if err != nil || !enumValueSet[value] {
+				return int64(0), fmt.Errorf("enum %v does not have value '%v'", name, v)
+			}
+			return value, nil
+		},
+	})
+	enumValueMapMap[normalizeTypeName(name)] = enumValueMap
+	return nil
+}
+
+// GetEnumValueMap 返回枚举名到枚举值的映射，Type 不是枚举时返回 nil
+func GetEnumValueMap(Type string) map[string]int64 {
+	return enumValueMapMap[normalizeTypeName(Type)]
+}
+
+func ParseValue(Type string, v string) (interface{}, error) {
+	p := GetTypeParser(Type)
+	if p == nil {
+		return "", fmt.Errorf("unknown type '%v'", Type)
+	}
+	return p.Parse(v)
+}
+
+func ValidateValue(Type string, v string) error {
+	_, err := ParseValue(Type, v)
+	return err
+}
+
+// ValidateLine 按 keyMap 中声明的类型校验一行数据，返回每个不合法单元格的错误
+func ValidateLine(dataArray []string, keyMap map[int]*KeyIndex) []error {
+	validateErrorSlice := make([]error, 0)
+	for i, v := range dataArray {
+		key, ok := keyMap[i]
+		if !ok {
+			continue
+		}
+		if err := ValidateValue(key.Type, v); err != nil {
+			validateErrorSlice = append(validateErrorSlice, fmt.Errorf("key %v: %v", key.Name, err))
+		}
+	}
+	return validateErrorSlice
+}
+
+// 去除类型名中的所有空白，使 `map<int, int>` 与 `map<int,int>` 等价
+func normalizeTypeName(Type string) string {
+	return strings.Join(strings.Fields(Type), "")
+}
+
+// ParseIntText 按十进制解析整数单元格，前导 0 不表示八进制（"010" 为 10），只有显式的 0x 前缀按十六进制解析，
+// 导出与关联检查中的比较都使用该函数，两边对同一个单元格得到相同的值
+func ParseIntText(v string, bitSize int) (int64, error) {
+	digits, base := splitIntegerBase(v)
+	i, err := strconv.ParseInt(digits, base, bitSize)
+	if numError, isNumError := err.(*strconv.NumError); isNumError {
+		numError.Num = v
+	}
+	return i, err
+}
+
+// ParseUintText 与 ParseIntText 相同的规则解析无符号整数
+func ParseUintText(v string, bitSize int) (uint64, error) {
+	digits, base := splitIntegerBase(v)
+	u, err := strconv.ParseUint(digits, base, bitSize)
+	if numError, isNumError := err.(*strconv.NumError); isNumError {
+		numError.Num = v
+	}
+	return u, err
+}
+
+// splitIntegerBase 去掉 0x 前缀并返回进制，符号保留在数字前
+func splitIntegerBase(v string) (string, int) {
+	sign := ""
+	if strings.HasPrefix(v, "-") || strings.HasPrefix(v, "+") {
+		sign, v = v[:1], v[1:]
+	}
+	if len(v) > 2 && (strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X")) {
+		return sign + v[2:], 16
+	}
+	return sign + v, 10
+}
+
+func newIntParser(bitSize int) func(string) (interface{}, error) {
+	return func(v string) (interface{}, error) {
+		if len(v) == 0 {
+			return int64(0), nil
+		}
+		s, err := ParseIntText(v, bitSize)
+		if err != nil {
+			return int64(0), err
+		}
+		return s, nil
+	}
+}
+
+func newUintParser(bitSize int) func(string) (interface{}, error) {
+	return func(v string) (interface{}, error) {
+		if len(v) == 0 {
+			return uint64(0), nil
+		}
+		s, err := ParseUintText(v, bitSize)
+		if err != nil {
+			return uint64(0), err
+		}
+		return s, nil
+	}
+}
+
+// newFloatParser 始终按 64 位解析，float32 的值只检查范围，避免 0.1 导出为 0.10000000149011612，
+// 需要 float32 的地方（如 protobuf）自行转换
+func newFloatParser(bitSize int) func(string) (interface{}, error) {
+	return func(v string) (interface{}, error) {
+		if len(v) == 0 {
+			return float64(0), nil
+		}
+		s, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return float64(0), err
+		}
+		if bitSize == 32 && math.Abs(s) > math.MaxFloat32 {
+			return float64(0), fmt.Errorf("value '%v' is out of float32 range", v)
+		}
+		return s, nil
+	}
+}
+
+func parseBool(v string) (interface{}, error) {
+	if len(v) == 0 {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, err
+	}
+	return b, nil
+}
+
+// 数组以 , 分隔，例如 1001,1002,1003
+func parseIntSlice(v string) (interface{}, error) {
+	r := make([]int64, 0)
+	if len(v) == 0 {
+		return r, nil
+	}
+	for _, element := range strings.Split(v, ",") {
+		s, err := ParseIntText(strings.TrimSpace(element), 64)
+		if err != nil {
+			return make([]int64, 0), err
+		}
+		r = append(r, s)
+	}
+	return r, nil
+}
+
+func parseStringSlice(v string) (interface{}, error) {
+	r := make([]string, 0)
+	if len(v) == 0 {
+		return r, nil
+	}
+	for _, element := range strings.Split(v, ",") {
+		r = append(r, strings.TrimSpace(element))
+	}
+	return r, nil
+}
+
+// map 以 , 分隔键值对，以 : 分隔键与值，例如 1:10,2:20
+func parseIntIntMap(v string) (interface{}, error) {
+	r := make(map[int64]int64)
+	if len(v) == 0 {
+		return r, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		index := strings.IndexRune(pair, ':')
+		if index == -1 {
+			return make(map[int64]int64), fmt.Errorf("map pair '%v' does not have ':'", pair)
+		}
+		k, err := ParseIntText(strings.TrimSpace(pair[:index]), 64)
+		if err != nil {
+			return make(map[int64]int64), err
+		}
+		value, err := ParseIntText(strings.TrimSpace(pair[index+1:]), 64)
+		if err != nil {
+			return make(map[int64]int64), err
+		}
+		if _, has := r[k]; has {
+			return make(map[int64]int64), fmt.Errorf("map key %v is duplicated", k)
+		}
+		r[k] = value
+	}
+	return r, nil
+}
+
+var defaultLocation = time.UTC
+
+// SetDefaultLocation 设置没有时区的时间文本所在的时区，默认为 UTC，使导出结果不依赖导表机器的本地时区
+func SetDefaultLocation(location *time.Location) {
+	defaultLocation = location
+}
+
+func GetDefaultLocation() *time.Location {
+	return defaultLocation
+}
+
+// 时间按 GetDefaultLocation 的时区解析，也接受 unix 秒
+func parseDatetime(v string) (interface{}, error) {
+	if len(v) == 0 {
+		return time.Time{}, nil
+	}
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(s, 0).In(defaultLocation), nil
+	}
+	for _, layout := range datetimeLayoutSlice {
+		if t, err := time.ParseInLocation(layout, v, defaultLocation); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("datetime '%v' does not match any layout", v)
+}
+
+// 时长接受 time.ParseDuration 的格式，纯数字视为秒
+func parseDuration(v string) (interface{}, error) {
+	if len(v) == 0 {
+		return time.Duration(0), nil
+	}
+	if s, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Duration(s) * time.Second, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return time.Duration(0), err
+	}
+	return d, nil
+}
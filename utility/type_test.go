@@ -0,0 +1,192 @@
+package utility
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	for _, c := range []struct {
+		Type    string
+		v       string
+		expect  interface{}
+		isError bool
+	}{
+		{"int", "1001", int64(1001), false},
+		{"int", "", int64(0), false},
+		{"int", "0x10", int64(16), false},
+		{"int", "-0X10", int64(-16), false},
+		{"int", "010", int64(10), false},
+		{"int", "08", int64(8), false},
+		{"int", "09", int64(9), false},
+		{"int", "-007", int64(-7), false},
+		{"int", "0b1", nil, true},
+		{"int", "0o7", nil, true},
+		{"int", "1_000", nil, true},
+		{"int", "0x", nil, true},
+		{"uint", "010", uint64(10), false},
+		{"uint", "0xff", uint64(255), false},
+		{"[]int", "01,08,0x10", []int64{1, 8, 16}, false},
+		{"map<int,int>", "01:08", map[int64]int64{1: 8}, false},
+		{"int8", "128", nil, true},
+		{"int", "abc", nil, true},
+		{"uint32", "4294967295", uint64(4294967295), false},
+		{"uint", "-1", nil, true},
+		{"float", "0.1", float64(0.1), false},
+		{"float", "1e39", nil, true},
+		{"double", "1e39", float64(1e39), false},
+		{"string", " a b ", " a b ", false},
+		{"bool", "true", true, false},
+		{"bool", "", false, false},
+		{"bool", "yes", nil, true},
+		{"[]int", "1, 2,3", []int64{1, 2, 3}, false},
+		{"[]int", "", []int64{}, false},
+		{"[]string", "a, b", []string{"a", "b"}, false},
+		{"map<int, int>", "1:10,2:20", map[int64]int64{1: 10, 2: 20}, false},
+		{"map<int,int>", "1:10,1:20", nil, true},
+		{"map<int,int>", "1", nil, true},
+		{"duration", "90", 90 * time.Second, false},
+		{"duration", "1m30s", 90 * time.Second, false},
+		{"unknown", "1", nil, true},
+	} {
+		v, err := ParseValue(c.Type, c.v)
+		if c.isError {
+			if err == nil {
+				t.Errorf("ParseValue(%q, %q) = %v, expect error", c.Type, c.v, v)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseValue(%q, %q) error: %v", c.Type, c.v, err)
+			continue
+		}
+		if !reflect.DeepEqual(v, c.expect) {
+			t.Errorf("ParseValue(%q, %q) = %#v, expect %#v", c.Type, c.v, v, c.expect)
+		}
+	}
+}
+
+func TestParseDatetime(t *testing.T) {
+	defer SetDefaultLocation(time.UTC)
+	for _, c := range []struct {
+		name     string
+		location *time.Location
+		v        string
+		expect   time.Time
+	}{
+		{"utc by default", nil, "2020-01-02 03:04:05", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"utc by default", nil, "2020/01/02 03:04:05", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"utc by default", nil, "2020-01-02", time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)},
+		{"unix seconds", nil, "1577905445", time.Unix(1577905445, 0)},
+		{"explicit offset", nil, "2020-01-02T03:04:05+08:00", time.Date(2020, 1, 1, 19, 4, 5, 0, time.UTC)},
+		{"project location", time.FixedZone("UTC+8", 8*3600), "2020-01-02 03:04:05", time.Date(2020, 1, 1, 19, 4, 5, 0, time.UTC)},
+		{"project location unix seconds", time.FixedZone("UTC+8", 8*3600), "1577905445", time.Unix(1577905445, 0)},
+	} {
+		SetDefaultLocation(time.UTC)
+		if c.location != nil {
+			SetDefaultLocation(c.location)
+		}
+		d, err := ParseValue("datetime", c.v)
+		if err != nil {
+			t.Errorf("%v: ParseValue(datetime, %q) error: %v", c.name, c.v, err)
+			continue
+		}
+		if !d.(time.Time).Equal(c.expect) {
+			t.Errorf("%v: ParseValue(datetime, %q) = %v, expect %v", c.name, c.v, d, c.expect)
+		}
+	}
+	if _, err := ParseValue("datetime", "tomorrow"); err == nil {
+		t.Errorf("ParseValue(datetime, tomorrow) expect error")
+	}
+}
+
+func TestProjectTimeZone(t *testing.T) {
+	defer SetDefaultLocation(time.UTC)
+	project, err := LoadProject(strings.NewReader(`{"TimeZone":"Etc/GMT-8"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := project.Apply(); err != nil {
+		t.Skipf("time zone database is not available: %v", err)
+	}
+	d, err := ParseValue("datetime", "2020-01-02 03:04:05")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := time.Date(2020, 1, 1, 19, 4, 5, 0, time.UTC); !d.(time.Time).Equal(expect) {
+		t.Errorf("ParseValue(datetime) with project time zone = %v, expect %v", d, expect)
+	}
+
+	project, err = LoadProject(strings.NewReader(`{"TimeZone":"Nowhere/Invalid"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := project.Apply(); err == nil {
+		t.Errorf("Apply with invalid time zone expect error")
+	}
+}
+
+func TestRegisterEnum(t *testing.T) {
+	if err := RegisterEnum("TestQuality", map[string]int64{"WHITE": 1, "GREEN": 2}); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		v       string
+		expect  int64
+		isError bool
+	}{
+		{"WHITE", 1, false},
+		{"2", 2, false},
+		{"", 0, false},
+		{"3", 0, true},
+		{"RED", 0, true},
+	} {
+		v, err := ParseValue("TestQuality", c.v)
+		if c.isError != (err != nil) {
+			t.Errorf("ParseValue(TestQuality, %q) error = %v, expect error %v", c.v, err, c.isError)
+			continue
+		}
+		if !c.isError && v != c.expect {
+			t.Errorf("ParseValue(TestQuality, %q) = %v, expect %v", c.v, v, c.expect)
+		}
+	}
+	if err := RegisterEnum("int", map[string]int64{"A": 1}); err == nil {
+		t.Errorf("RegisterEnum(int) expect conflict error")
+	}
+}
+
+func TestValidateLine(t *testing.T) {
+	keyMap := map[int]*KeyIndex{
+		0: {Name: "id", Type: "int"},
+		2: {Name: "rate", Type: "float"},
+	}
+	if errorSlice := ValidateLine([]string{"1", "skip", "0.5"}, keyMap); len(errorSlice) != 0 {
+		t.Errorf("ValidateLine valid line errors: %v", errorSlice)
+	}
+	if errorSlice := ValidateLine([]string{"a", "skip", "b"}, keyMap); len(errorSlice) != 2 {
+		t.Errorf("ValidateLine invalid line errors: %v, expect 2", errorSlice)
+	}
+}
+
+func TestProcessLine(t *testing.T) {
+	keyMap := map[int]*KeyIndex{
+		0: {Name: "id", Type: "int"},
+		1: {Name: "name", Type: "string"},
+		2: {Name: "rate", Type: "float"},
+		3: {Name: "count", Type: "int", Default: "5"},
+	}
+	for _, c := range []struct {
+		dataArray []string
+		expect    []interface{}
+	}{
+		{[]string{"1", "a", "0.5", "2"}, []interface{}{int64(1), "a", 0.5, int64(2)}},
+		{[]string{"0", "", "0", "0"}, []interface{}{int64(0), "", float64(0), int64(0)}},
+		{[]string{"", "", "", ""}, []interface{}{nil, "", nil, int64(5)}},
+	} {
+		if r := ProcessLine(c.dataArray, keyMap); !reflect.DeepEqual(r, c.expect) {
+			t.Errorf("ProcessLine(%q) = %#v, expect %#v", c.dataArray, r, c.expect)
+		}
+	}
+}
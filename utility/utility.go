@@ -3,17 +3,14 @@ package utility
 import (
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"io"
-	"strconv"
 	"strings"
-
-	"golang.org/x/text/encoding/simplifiedchinese"
-	"golang.org/x/text/transform"
 )
 
+// CompareGameDataJsonObjectData 不知道列类型时比较 json 中的值与引用的内容，知道类型时使用 CompareValue
 func CompareGameDataJsonObjectData(data interface{}, content string) bool {
-	return strings.Compare(fmt.Sprintf("%v", data), content) == 0
+	equal, _ := CompareValue("", data, content)
+	return equal
 }
 
 func TraitFileName(fullFilename, extendType string) string {
@@ -25,7 +22,7 @@ func ConvertFormationMapToJson(m map[string]map[string]string) map[string]string
 	for fileName, formationMap := range m {
 		j, e := json.Marshal(formationMap)
 		if e != nil {
-			fmt.Printf("Error: json marshal formation map %v occurs error: %v\n", formationMap, e)
+			GetReporter().Errorf("json marshal formation map %v occurs error: %v", formationMap, e)
 			return nil
 		}
 		formationJsonMap[fileName] = string(j)
@@ -34,155 +31,218 @@ func ConvertFormationMapToJson(m map[string]map[string]string) map[string]string
 	return formationJsonMap
 }
 
+// ConvertFileContentToJson 按 SetDefaultEncoding 设置的编码读取 CSV，默认自动检测编码，
+// 不知道表名，项目配置中声明的主键不生效，知道表名时使用 ConvertFileContentToJsonWithName
 func ConvertFileContentToJson(r io.Reader) (string, map[string]string, error) {
-	transReader := transform.NewReader(r, simplifiedchinese.GBK.NewDecoder())
-	fileReader := csv.NewReader(transReader)
-	err, jsonString, formationMap := ProcessCsvAndFormation(fileReader)
+	return ConvertFileContentToJsonWithName("", r)
+}
+
+func ConvertFileContentToJsonWithEncoding(r io.Reader, encodingName string) (string, map[string]string, error) {
+	return ConvertFileContentToJsonWithNameAndEncoding("", r, encodingName)
+}
+
+// ConvertFileContentToJsonWithName name 为表名，一般为 TraitFileName(filepath.Base(path), ".csv")
+func ConvertFileContentToJsonWithName(name string, r io.Reader) (string, map[string]string, error) {
+	return ConvertFileContentToJsonWithNameAndEncoding(name, r, defaultEncodingName)
+}
+
+func ConvertFileContentToJsonWithNameAndEncoding(name string, r io.Reader, encodingName string) (string, map[string]string, error) {
+	builder := &strings.Builder{}
+	formationMap, err := ConvertFileContentToJsonWriter(builder, name, r, encodingName)
 	if err != nil {
 		return "", nil, err
 	}
-	return jsonString, formationMap, nil
+	return builder.String(), formationMap, nil
+}
+
+// ConvertFileContentToJsonWriter 边读取边将 json 写入 w，不在内存中保留整张表或整个 json，返回错误时 w 中的内容不完整
+func ConvertFileContentToJsonWriter(w io.Writer, name string, r io.Reader, encodingName string) (map[string]string, error) {
+	transReader, err := NewDecodeReader(r, encodingName)
+	if err != nil {
+		return nil, err
+	}
+	return ProcessCsvAndFormationToWriter(w, name, csv.NewReader(transReader), defaultHeaderLayout)
+}
+
+// RowReader 按行读取配置表，*csv.Reader 与 xlsx 的 sheet 读取器都满足该接口
+type RowReader interface {
+	Read() ([]string, error)
 }
 
 type KeyIndex struct {
-	Name  string
-	Type  string
-	Index int
+	Name        string
+	Type        string
+	Index       int
+	Default     string
+	Comment     string
+	Description string
+	Targets     ExportTargetSet
+	PrimaryKey  bool
 }
 
-func ProcessCsvAndFormation(fileReader *csv.Reader) (error, string, map[string]string) {
-	_, _ = fileReader.Read()                 //注释行
-	formationArray, err := fileReader.Read() //策划注释行
-	if err != nil {
-		return err, "", nil
-	}
+// ProcessCsvAndFormation 按 SetDefaultHeaderLayout 设置的表头布局处理配置表，不知道表名时项目配置中声明的主键不生效
+func ProcessCsvAndFormation(fileReader RowReader) (error, string, map[string]string) {
+	return ProcessCsvAndFormationWithNameAndLayout("", fileReader, defaultHeaderLayout)
+}
 
-	opsArray, err := fileReader.Read()
+func ProcessCsvAndFormationWithLayout(fileReader RowReader, layout *HeaderLayout) (error, string, map[string]string) {
+	return ProcessCsvAndFormationWithNameAndLayout("", fileReader, layout)
+}
+
+// ProcessCsvAndFormationWithName name 为表名，用于查找项目配置中声明的主键
+func ProcessCsvAndFormationWithName(name string, fileReader RowReader) (error, string, map[string]string) {
+	return ProcessCsvAndFormationWithNameAndLayout(name, fileReader, defaultHeaderLayout)
+}
+
+func ProcessCsvAndFormationWithNameAndLayout(name string, fileReader RowReader, layout *HeaderLayout) (error, string, map[string]string) {
+	builder := &strings.Builder{}
+	formationMap, err := ProcessCsvAndFormationToWriter(builder, name, fileReader, layout)
 	if err != nil {
 		return err, "", nil
 	}
+	return nil, builder.String(), formationMap
+}
 
-	keyArray, err := fileReader.Read()
+// ProcessCsvAndFormationToWriter 通过 TableLoader.WriteJSON 边读取边将导出到服务器的 json 写入 w，返回每列的 formation，
+// 返回错误时 w 中的内容不完整
+func ProcessCsvAndFormationToWriter(w io.Writer, name string, fileReader RowReader, layout *HeaderLayout) (map[string]string, error) {
+	loader, err := NewTableLoader(name, fileReader, layout, EXPORT_SERVER)
 	if err != nil {
-		return err, "", nil
+		return nil, err
 	}
+	if err := loader.WriteJSON(w); err != nil {
+		return nil, err
+	}
+	return loader.FormationMap, nil
+}
 
-	typeArray, err := fileReader.Read()
+func ProcessCsv(fileReader RowReader) (error, string) {
+	return ProcessCsvWithNameAndLayout("", fileReader, defaultHeaderLayout)
+}
+
+func ProcessCsvWithLayout(fileReader RowReader, layout *HeaderLayout) (error, string) {
+	return ProcessCsvWithNameAndLayout("", fileReader, layout)
+}
+
+func ProcessCsvWithName(name string, fileReader RowReader) (error, string) {
+	return ProcessCsvWithNameAndLayout(name, fileReader, defaultHeaderLayout)
+}
+
+func ProcessCsvWithNameAndLayout(name string, fileReader RowReader, layout *HeaderLayout) (error, string) {
+	err, jsonString, _ := ProcessCsvAndFormationWithNameAndLayout(name, fileReader, layout)
+	return err, jsonString
+}
+
+// ReadKeyIndex 按布局读取表头，返回导出到服务器的列的 KeyIndex、Format 以及每列的 formation
+func ReadKeyIndex(fileReader RowReader, layout *HeaderLayout) (map[int]*KeyIndex, map[string]int, map[string]string, error) {
+	header, err := layout.ReadHeader(fileReader)
 	if err != nil {
-		return err, "", nil
+		return nil, nil, nil, err
 	}
+	keyMap, format, formationMap := BuildKeyIndex(header, EXPORT_SERVER)
+	return keyMap, format, formationMap, nil
+}
 
+// BuildKeyIndex 按 ops 行筛选导出到 target 的列
+func BuildKeyIndex(header *Header, target ExportTarget) (map[int]*KeyIndex, map[string]int, map[string]string) {
 	keyMap := map[int]*KeyIndex{}
 	format := map[string]int{}
 	formationMap := make(map[string]string)
 
 	arrayIndex := 0
-	for i, v := range keyArray {
-		ops := opsArray[i]
-		if ops == "client" || ops == "none" {
+	for i, v := range header.KeyRow() {
+		targetSet := ParseExportTargetSet(header.Cell(HEADER_OPS, i))
+		if !targetSet.Contains(target) {
 			continue
 		}
 
 		key := &KeyIndex{}
 		key.Name = v
-		key.Type = typeArray[i]
+		key.Type = header.Type(i)
 		key.Index = arrayIndex
+		key.Default = header.Cell(HEADER_DEFAULT, i)
+		key.Comment = header.Cell(HEADER_COMMENT, i)
+		key.Description = header.Cell(HEADER_DESCRIPTION, i)
+		key.Targets = targetSet
+		key.PrimaryKey = IsPrimaryKeyOps(header.Cell(HEADER_OPS, i))
 
 		keyMap[i] = key
 		format[v] = arrayIndex
-		formationMap[v] = formationArray[i]
+		formationMap[v] = header.Cell(HEADER_FORMATION, i)
 		arrayIndex++
 	}
 
-	data := make([][]interface{}, 0, 128)
-
-	for {
-
-		line, err := fileReader.Read()
-		if err == io.EOF {
-			break
-		}
-
-		o := ProcessLine(line, keyMap)
-		data = append(data, o)
-	}
-
-	jsonObject := &struct {
-		Format map[string]int  `json:"Format"`
-		Data   [][]interface{} `json:"Data"`
-	}{}
-
-	jsonObject.Format = format
-	jsonObject.Data = data
-
-	jsonString, err := json.Marshal(jsonObject)
-	return err, string(jsonString), formationMap
+	return keyMap, format, formationMap
 }
 
-func ProcessCsv(fileReader *csv.Reader) (error, string) {
-	_, _ = fileReader.Read() //注释行
-	_, _ = fileReader.Read() //策划注释行
+// ProcessCsvAndFormationForTargets 读取一次配置表，为每个导出目标分别生成 json；
+// EXPORT_ALL 的 json 额外附带 Export 记录每列的导出目标，供关联检查按目标校验。
+// 返回的 formation 覆盖所有导出的列，包括只导出到客户端的列
+func ProcessCsvAndFormationForTargets(fileReader RowReader, layout *HeaderLayout, targetSlice []ExportTarget) (map[ExportTarget]string, map[string]string, error) {
+	return ProcessCsvAndFormationForTargetsWithName("", fileReader, layout, targetSlice)
+}
 
-	opsArray, err := fileReader.Read()
-	if err != nil {
-		return err, ""
+// ProcessCsvAndFormationForTargetsWithName name 为表名，用于查找项目配置中声明的主键
+func ProcessCsvAndFormationForTargetsWithName(name string, fileReader RowReader, layout *HeaderLayout, targetSlice []ExportTarget) (map[ExportTarget]string, map[string]string, error) {
+	builderMap := make(map[ExportTarget]*strings.Builder)
+	writerMap := make(map[ExportTarget]io.Writer)
+	for _, target := range targetSlice {
+		builderMap[target] = &strings.Builder{}
+		writerMap[target] = builderMap[target]
 	}
-
-	keyArray, err := fileReader.Read()
+	formationMap, err := ProcessCsvAndFormationForTargetsToWriter(writerMap, name, fileReader, layout)
 	if err != nil {
-		return nil, ""
+		return nil, nil, err
+	}
+	jsonStringMap := make(map[ExportTarget]string)
+	for target, builder := range builderMap {
+		jsonStringMap[target] = builder.String()
 	}
+	return jsonStringMap, formationMap, nil
+}
 
-	typeArray, err := fileReader.Read()
+// ProcessCsvAndFormationForTargetsToWriter 读取一次配置表，边读取边将每个导出目标的 json 写入 writerMap 中对应的 w，
+// 返回错误时各个 w 中的内容不完整
+func ProcessCsvAndFormationForTargetsToWriter(writerMap map[ExportTarget]io.Writer, name string, fileReader RowReader, layout *HeaderLayout) (map[string]string, error) {
+	loader, err := NewTableLoader(name, fileReader, layout, EXPORT_ALL)
 	if err != nil {
-		return nil, ""
+		return nil, err
 	}
 
-	keyMap := map[int]*KeyIndex{}
-	format := map[string]int{}
-
-	arrayIndex := 0
-	for i, v := range keyArray {
-		ops := opsArray[i]
-		if ops == "client" || ops == "none" {
-			continue
+	jsonWriterMap := make(map[ExportTarget]*tableJsonWriter)
+	for target, w := range writerMap {
+		_, format, _ := BuildKeyIndex(loader.Header, target)
+		jsonWriterMap[target], err = newTableJsonWriter(w, format)
+		if err != nil {
+			return nil, err
 		}
-
-		key := &KeyIndex{}
-		key.Name = v
-		key.Type = typeArray[i]
-		key.Index = arrayIndex
-
-		keyMap[i] = key
-		format[v] = arrayIndex
-		arrayIndex++
 	}
 
-	data := make([][]interface{}, 0, 128)
-
-	for {
-
-		line, err := fileReader.Read()
-		if err == io.EOF {
-			break
+	if err := loader.forEachRow(func(row []interface{}) error {
+		for target, jsonWriter := range jsonWriterMap {
+			if err := jsonWriter.WriteRow(loader.Project(row, target)); err != nil {
+				return err
+			}
 		}
-
-		o := ProcessLine(line, keyMap)
-		data = append(data, o)
+		return nil
+	}); err != nil {
+		return nil, err
 	}
 
-	jsonObject := &struct {
-		Format map[string]int  `json:"Format"`
-		Data   [][]interface{} `json:"Data"`
-	}{}
-
-	jsonObject.Format = format
-	jsonObject.Data = data
-
-	jsonString, err := json.Marshal(jsonObject)
-	return err, string(jsonString)
+	for target, jsonWriter := range jsonWriterMap {
+		var export map[string][]string
+		if target == EXPORT_ALL {
+			export = keySliceExport(loader.KeySlice)
+		}
+		if err := jsonWriter.Close(export, primaryKeyNames(loader.Name, loader.KeySlice, target), keySliceTypes(loader.KeySlice, target)); err != nil {
+			return nil, err
+		}
+	}
+	return loader.FormationMap, nil
 }
 
+// ProcessLine 按类型解析一行，空单元格使用默认值，没有默认值时 string 以外的类型为 nil（json 中为 null），
+// 关联检查据此区分空单元格与 0
 func ProcessLine(dataArray []string, keyMap map[int]*KeyIndex) []interface{} {
 	r := make([]interface{}, 0, len(dataArray))
 	for i, v := range dataArray {
@@ -190,22 +250,32 @@ func ProcessLine(dataArray []string, keyMap map[int]*KeyIndex) []interface{} {
 		if ok == false {
 			continue
 		}
+		if len(v) == 0 {
+			v = key.Default
+		}
+		if len(v) == 0 && isNullableType(key.Type) {
+			r = append(r, nil)
+			continue
+		}
 		r = append(r, GetParseString(key.Type, v))
 	}
 	return r
 }
 
+// isNullableType 判断空单元格是否导出为 nil，string 的空字符串本身就能表示空单元格
+func isNullableType(Type string) bool {
+	p := GetTypeParser(Type)
+	return p != nil && p.Name != "string"
+}
+
 func GetParseString(Type string, v string) interface{} {
-	switch Type {
-	case "int", "int64", "int32":
-		s, _ := strconv.ParseInt(v, 0, 64)
-		return s
-	case "double":
-		s, _ := strconv.ParseFloat(v, 64)
-		return s
-	case "string":
-		return v
-	default:
-	}
-	return ""
+	p := GetTypeParser(Type)
+	if p == nil {
+		return ""
+	}
+	s, err := p.Parse(v)
+	if err != nil {
+		s, _ = p.Parse("")
+	}
+	return s
 }
@@ -0,0 +1,487 @@
+package utility
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	xlsxWorkbookPath      = "xl/workbook.xml"
+	xlsxWorkbookRelsPath  = "xl/_rels/workbook.xml.rels"
+	xlsxSharedStringsPath = "xl/sharedStrings.xml"
+	xlsxStylesPath        = "xl/styles.xml"
+)
+
+type xlsxWorkbookXml struct {
+	WorkbookPr struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	SheetSlice []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationshipsXml struct {
+	RelationshipSlice []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxStringItemXml struct {
+	Text     string `xml:"t"`
+	RunSlice []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (si *xlsxStringItemXml) String() string {
+	if len(si.RunSlice) == 0 {
+		return si.Text
+	}
+	builder := strings.Builder{}
+	for _, run := range si.RunSlice {
+		builder.WriteString(run.Text)
+	}
+	return builder.String()
+}
+
+type xlsxSharedStringsXml struct {
+	StringItemSlice []xlsxStringItemXml `xml:"si"`
+}
+
+type xlsxStylesXml struct {
+	NumFmtSlice []struct {
+		NumFmtId   int    `xml:"numFmtId,attr"`
+		FormatCode string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfSlice []struct {
+		NumFmtId int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+type xlsxRowXml struct {
+	R         int `xml:"r,attr"`
+	CellSlice []struct {
+		R          string            `xml:"r,attr"`
+		T          string            `xml:"t,attr"`
+		S          int               `xml:"s,attr"`
+		V          string            `xml:"v"`
+		InlineText xlsxStringItemXml `xml:"is"`
+	} `xml:"c"`
+}
+
+type xlsxSheet struct {
+	Name string
+	Path string
+}
+
+// XlsxWorkbook 只依赖标准库读取 .xlsx（zip + SpreadsheetML），每个 sheet 视为一张配置表
+type XlsxWorkbook struct {
+	zipReader         *zip.Reader
+	closer            io.Closer
+	sheetSlice        []*xlsxSheet
+	sharedStringSlice []string
+	// dateStyleSlice 按单元格样式下标记录数字格式是否为日期，date1904 为工作簿是否使用 1904 日期系统
+	dateStyleSlice []bool
+	date1904       bool
+}
+
+func OpenXlsx(filePath string) (*XlsxWorkbook, error) {
+	zipReadCloser, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	workbook, err := newXlsxWorkbook(&zipReadCloser.Reader)
+	if err != nil {
+		zipReadCloser.Close()
+		return nil, err
+	}
+	workbook.closer = zipReadCloser
+	return workbook, nil
+}
+
+func NewXlsxWorkbook(r io.ReaderAt, size int64) (*XlsxWorkbook, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, err
+	}
+	return newXlsxWorkbook(zipReader)
+}
+
+func newXlsxWorkbook(zipReader *zip.Reader) (*XlsxWorkbook, error) {
+	workbook := &XlsxWorkbook{zipReader: zipReader}
+
+	workbookXml := &xlsxWorkbookXml{}
+	if err := workbook.decodeFile(xlsxWorkbookPath, workbookXml); err != nil {
+		return nil, err
+	}
+	workbook.date1904 = workbookXml.WorkbookPr.Date1904 == "1" || workbookXml.WorkbookPr.Date1904 == "true"
+	relationshipsXml := &xlsxRelationshipsXml{}
+	if err := workbook.decodeFile(xlsxWorkbookRelsPath, relationshipsXml); err != nil {
+		return nil, err
+	}
+	relationshipTargetMap := make(map[string]string)
+	for _, relationship := range relationshipsXml.RelationshipSlice {
+		relationshipTargetMap[relationship.Id] = relationship.Target
+	}
+	for _, sheet := range workbookXml.SheetSlice {
+		target, hasTarget := relationshipTargetMap[sheet.Id]
+		if !hasTarget {
+			return nil, fmt.Errorf("xlsx sheet %v relationship %v does not exist", sheet.Name, sheet.Id)
+		}
+		if strings.HasPrefix(target, "/") {
+			target = target[1:]
+		} else {
+			target = path.Join(path.Dir(xlsxWorkbookPath), target)
+		}
+		workbook.sheetSlice = append(workbook.sheetSlice, &xlsxSheet{Name: sheet.Name, Path: target})
+	}
+
+	// sharedStrings.xml 在没有字符串的工作簿中不存在
+	if workbook.findFile(xlsxSharedStringsPath) != nil {
+		sharedStringsXml := &xlsxSharedStringsXml{}
+		if err := workbook.decodeFile(xlsxSharedStringsPath, sharedStringsXml); err != nil {
+			return nil, err
+		}
+		for index := range sharedStringsXml.StringItemSlice {
+			workbook.sharedStringSlice = append(workbook.sharedStringSlice, sharedStringsXml.StringItemSlice[index].String())
+		}
+	}
+
+	// Excel 把日期存为数字，只能通过单元格样式的数字格式区分
+	if workbook.findFile(xlsxStylesPath) != nil {
+		stylesXml := &xlsxStylesXml{}
+		if err := workbook.decodeFile(xlsxStylesPath, stylesXml); err != nil {
+			return nil, err
+		}
+		formatCodeMap := make(map[int]string)
+		for _, numFmt := range stylesXml.NumFmtSlice {
+			formatCodeMap[numFmt.NumFmtId] = numFmt.FormatCode
+		}
+		for _, cellXf := range stylesXml.CellXfSlice {
+			formatCode, isCustom := formatCodeMap[cellXf.NumFmtId]
+			if isCustom {
+				workbook.dateStyleSlice = append(workbook.dateStyleSlice, isXlsxDateFormatCode(formatCode))
+			} else {
+				workbook.dateStyleSlice = append(workbook.dateStyleSlice, isXlsxBuiltInDateFormat(cellXf.NumFmtId))
+			}
+		}
+	}
+
+	return workbook, nil
+}
+
+func (w *XlsxWorkbook) findFile(name string) *zip.File {
+	for _, f := range w.zipReader.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func (w *XlsxWorkbook) decodeFile(name string, v interface{}) error {
+	f := w.findFile(name)
+	if f == nil {
+		return fmt.Errorf("xlsx file %v does not exist", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return xml.NewDecoder(r).Decode(v)
+}
+
+func (w *XlsxWorkbook) Close() error {
+	if w.closer == nil {
+		return nil
+	}
+	return w.closer.Close()
+}
+
+func (w *XlsxWorkbook) SheetNames() []string {
+	nameSlice := make([]string, 0, len(w.sheetSlice))
+	for _, sheet := range w.sheetSlice {
+		nameSlice = append(nameSlice, sheet.Name)
+	}
+	return nameSlice
+}
+
+// SheetReader 返回逐行读取指定 sheet 的 RowReader，使用完毕后需要 Close
+func (w *XlsxWorkbook) SheetReader(name string) (*XlsxSheetReader, error) {
+	for _, sheet := range w.sheetSlice {
+		if sheet.Name != name {
+			continue
+		}
+		f := w.findFile(sheet.Path)
+		if f == nil {
+			return nil, fmt.Errorf("xlsx sheet %v file %v does not exist", sheet.Name, sheet.Path)
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		return &XlsxSheetReader{
+			readCloser:        r,
+			decoder:           xml.NewDecoder(r),
+			sharedStringSlice: w.sharedStringSlice,
+			dateStyleSlice:    w.dateStyleSlice,
+			date1904:          w.date1904,
+		}, nil
+	}
+	return nil, fmt.Errorf("xlsx sheet %v does not exist", name)
+}
+
+// XlsxSheetReader 流式解析 sheet 的 <row>，行内缺失的单元格补为空字符串。
+// Excel 不会写出空行，按 <row r="..."> 的行号为缺失的行返回空切片，使表头各行的位置与表格中一致，
+// 没有内容的行同样返回空切片，由 TableLoader 跳过数据中的空行
+type XlsxSheetReader struct {
+	readCloser        io.ReadCloser
+	decoder           *xml.Decoder
+	sharedStringSlice []string
+	dateStyleSlice    []bool
+	date1904          bool
+	width             int
+	// rowNumber 已返回的最后一行的行号，gapCount 为 pendingRow 之前还需要返回的空行数
+	rowNumber  int
+	gapCount   int
+	pendingRow []string
+}
+
+func (r *XlsxSheetReader) Read() ([]string, error) {
+	if r.gapCount > 0 {
+		r.gapCount--
+		r.rowNumber++
+		return []string{}, nil
+	}
+	if r.pendingRow != nil {
+		row := r.pendingRow
+		r.pendingRow = nil
+		r.rowNumber++
+		return row, nil
+	}
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		startElement, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch startElement.Name.Local {
+		case "dimension":
+			for _, attr := range startElement.Attr {
+				if attr.Name.Local == "ref" {
+					r.width = xlsxDimensionWidth(attr.Value)
+				}
+			}
+		case "row":
+			rowXml := &xlsxRowXml{}
+			if err := r.decoder.DecodeElement(rowXml, &startElement); err != nil {
+				return nil, err
+			}
+			row, err := r.convertRow(rowXml)
+			if err != nil {
+				return nil, err
+			}
+			rowNumber := rowXml.R
+			if rowNumber <= r.rowNumber {
+				rowNumber = r.rowNumber + 1
+			}
+			if gap := rowNumber - r.rowNumber - 1; gap > 0 {
+				r.gapCount = gap - 1
+				r.pendingRow = row
+				r.rowNumber++
+				return []string{}, nil
+			}
+			r.rowNumber = rowNumber
+			return row, nil
+		}
+	}
+}
+
+func (r *XlsxSheetReader) convertRow(rowXml *xlsxRowXml) ([]string, error) {
+	row := make([]string, 0, r.width)
+	for _, cell := range rowXml.CellSlice {
+		columnIndex := len(row)
+		if len(cell.R) != 0 {
+			index, err := xlsxColumnIndex(cell.R)
+			if err != nil {
+				return nil, err
+			}
+			columnIndex = index
+		}
+		for len(row) <= columnIndex {
+			row = append(row, "")
+		}
+		switch cell.T {
+		case "s":
+			sharedStringIndex, err := strconv.Atoi(cell.V)
+			if err != nil || sharedStringIndex < 0 || sharedStringIndex >= len(r.sharedStringSlice) {
+				return nil, fmt.Errorf("xlsx cell %v shared string index '%v' is invalid", cell.R, cell.V)
+			}
+			row[columnIndex] = r.sharedStringSlice[sharedStringIndex]
+		case "inlineStr":
+			row[columnIndex] = cell.InlineText.String()
+		case "b":
+			if cell.V == "1" {
+				row[columnIndex] = "TRUE"
+			} else {
+				row[columnIndex] = "FALSE"
+			}
+		case "", "n":
+			row[columnIndex] = cell.V
+			if cell.S >= 0 && cell.S < len(r.dateStyleSlice) && r.dateStyleSlice[cell.S] {
+				if date, ok := xlsxSerialToDate(cell.V, r.date1904); ok {
+					row[columnIndex] = date
+				}
+			}
+		default:
+			row[columnIndex] = cell.V
+		}
+	}
+
+	empty := true
+	for _, v := range row {
+		if len(v) != 0 {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		return []string{}, nil
+	}
+
+	if len(row) > r.width {
+		r.width = len(row)
+	}
+	for len(row) < r.width {
+		row = append(row, "")
+	}
+	return row, nil
+}
+
+func (r *XlsxSheetReader) Close() error {
+	return r.readCloser.Close()
+}
+
+// xlsxColumnIndex 将 A1 形式的单元格引用转为从 0 开始的列下标
+func xlsxColumnIndex(ref string) (int, error) {
+	index := 0
+	letterCount := 0
+	for _, c := range ref {
+		if c >= 'A' && c <= 'Z' {
+			index = index*26 + int(c-'A'+1)
+			letterCount++
+		} else if c >= 'a' && c <= 'z' {
+			index = index*26 + int(c-'a'+1)
+			letterCount++
+		} else {
+			break
+		}
+	}
+	if letterCount == 0 {
+		return 0, fmt.Errorf("xlsx cell reference '%v' is invalid", ref)
+	}
+	return index - 1, nil
+}
+
+// isXlsxBuiltInDateFormat 内置数字格式中包含日期的格式，只有时间的格式仍按数字处理
+func isXlsxBuiltInDateFormat(numFmtId int) bool {
+	return numFmtId >= 14 && numFmtId <= 17 || numFmtId == 22 || numFmtId >= 27 && numFmtId <= 36 || numFmtId >= 50 && numFmtId <= 58
+}
+
+// isXlsxDateFormatCode 自定义数字格式去掉引号中的文本、转义字符与 [...] 后含有年或日即为日期格式
+func isXlsxDateFormatCode(formatCode string) bool {
+	inQuote := false
+	inBracket := false
+	escaped := false
+	for _, c := range strings.ToLower(formatCode) {
+		switch {
+		case escaped:
+			escaped = false
+		case inQuote:
+			inQuote = c != '"'
+		case inBracket:
+			inBracket = c != ']'
+		case c == '\\' || c == '_' || c == '*':
+			escaped = true
+		case c == '"':
+			inQuote = true
+		case c == '[':
+			inBracket = true
+		case c == 'y' || c == 'd':
+			return true
+		}
+	}
+	return false
+}
+
+// xlsxSerialToDate 将 Excel 日期序列号转为 datetime 类型可以解析的文本，没有时间部分时只输出日期
+func xlsxSerialToDate(v string, date1904 bool) (string, bool) {
+	serial, err := strconv.ParseFloat(v, 64)
+	if err != nil || serial < 0 {
+		return "", false
+	}
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	} else if serial < 60 {
+		// 1900 日期系统把 1900 年当作闰年，3 月 1 日之前的序列号多算了一天
+		serial++
+	}
+	second := int64(serial*86400 + 0.5)
+	t := epoch.Add(time.Duration(second) * time.Second)
+	if second%86400 == 0 {
+		return t.Format("2006-01-02"), true
+	}
+	return t.Format("2006-01-02 15:04:05"), true
+}
+
+func xlsxDimensionWidth(ref string) int {
+	colonIndex := strings.IndexRune(ref, ':')
+	if colonIndex == -1 {
+		return 0
+	}
+	index, err := xlsxColumnIndex(ref[colonIndex+1:])
+	if err != nil {
+		return 0
+	}
+	return index + 1
+}
+
+// ConvertXlsxToJson 将工作簿中每个 sheet 按 ProcessCsvAndFormationWithName 处理，sheet 名即表名
+func ConvertXlsxToJson(filePath string) (map[string]string, map[string]map[string]string, error) {
+	workbook, err := OpenXlsx(filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer workbook.Close()
+
+	jsonStringMap := make(map[string]string)
+	fileFormationMap := make(map[string]map[string]string)
+	for _, sheetName := range workbook.SheetNames() {
+		sheetReader, err := workbook.SheetReader(sheetName)
+		if err != nil {
+			return nil, nil, err
+		}
+		err, jsonString, formationMap := ProcessCsvAndFormationWithName(sheetName, sheetReader)
+		sheetReader.Close()
+		if err != nil {
+			return nil, nil, fmt.Errorf("xlsx %v sheet %v: %v", filePath, sheetName, err)
+		}
+		jsonStringMap[sheetName] = jsonString
+		fileFormationMap[sheetName] = formationMap
+	}
+	return jsonStringMap, fileFormationMap, nil
+}
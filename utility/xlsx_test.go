@@ -0,0 +1,253 @@
+package utility
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+const testXlsxWorkbookXml = `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="Item" sheetId="1" r:id="rId1"/></sheets>
+</workbook>`
+
+const testXlsxWorkbookRelsXml = `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>
+</Relationships>`
+
+const testXlsxSharedStringsXml = `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<si><t>server</t></si><si><t>id</t></si><si><t>name</t></si><si><t>int</t></si><si><t>string</t></si>
+<si><r><t>swo</t></r><r><t>rd</t></r></si>
+</sst>`
+
+// 第 1 行注释与第 2 行 formation 为空，Excel 不会写出；第 7 行为数据中的空行
+const testXlsxSheetXml = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:B8"/>
+<sheetData>
+<row r="3"><c r="A3" t="s"><v>0</v></c><c r="B3" t="s"><v>0</v></c></row>
+<row r="4"><c r="A4" t="s"><v>1</v></c><c r="B4" t="s"><v>2</v></c></row>
+<row r="5"><c r="A5" t="s"><v>3</v></c><c r="B5" t="s"><v>4</v></c></row>
+<row r="6"><c r="A6"><v>1001</v></c><c r="B6" t="s"><v>5</v></c></row>
+<row r="8"><c r="A8"><v>1002</v></c><c r="B8" t="inlineStr"><is><t>shield</t></is></c></row>
+</sheetData>
+</worksheet>`
+
+func newTestXlsxWorkbook(t *testing.T, sheetXml string) *XlsxWorkbook {
+	content := newTestXlsxContent(t, sheetXml)
+	workbook, err := NewXlsxWorkbook(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return workbook
+}
+
+// newTestXlsxContent 生成只有一个名为 Item 的 sheet 的 xlsx 文件内容
+func newTestXlsxContent(t *testing.T, sheetXml string) []byte {
+	return newTestXlsxZip(t, map[string]string{
+		xlsxWorkbookPath:           testXlsxWorkbookXml,
+		xlsxWorkbookRelsPath:       testXlsxWorkbookRelsXml,
+		xlsxSharedStringsPath:      testXlsxSharedStringsXml,
+		"xl/worksheets/sheet1.xml": sheetXml,
+	})
+}
+
+func newTestXlsxZip(t *testing.T, fileMap map[string]string) []byte {
+	buffer := &bytes.Buffer{}
+	zipWriter := zip.NewWriter(buffer)
+	for name, content := range fileMap {
+		w, err := zipWriter.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zipWriter.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestXlsxSheetReaderEmptyRow(t *testing.T) {
+	workbook := newTestXlsxWorkbook(t, testXlsxSheetXml)
+	if names := workbook.SheetNames(); !reflect.DeepEqual(names, []string{"Item"}) {
+		t.Fatalf("SheetNames() = %v", names)
+	}
+	sheetReader, err := workbook.SheetReader("Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sheetReader.Close()
+
+	expect := [][]string{
+		{},
+		{},
+		{"server", "server"},
+		{"id", "name"},
+		{"int", "string"},
+		{"1001", "sword"},
+		{},
+		{"1002", "shield"},
+	}
+	for index, expectRow := range expect {
+		row, err := sheetReader.Read()
+		if err != nil {
+			t.Fatalf("row %v: %v", index+1, err)
+		}
+		if !reflect.DeepEqual(row, expectRow) {
+			t.Errorf("row %v = %q, expect %q", index+1, row, expectRow)
+		}
+	}
+	if _, err := sheetReader.Read(); err != io.EOF {
+		t.Errorf("read after last row error = %v, expect io.EOF", err)
+	}
+}
+
+func TestXlsxEmptyHeaderRowLayout(t *testing.T) {
+	workbook := newTestXlsxWorkbook(t, testXlsxSheetXml)
+	sheetReader, err := workbook.SheetReader("Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sheetReader.Close()
+
+	loader, err := NewTableLoader("Item", sheetReader, DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(table.Format, map[string]int{"id": 0, "name": 1}) {
+		t.Errorf("Format = %v", table.Format)
+	}
+	expect := [][]interface{}{{int64(1001), "sword"}, {int64(1002), "shield"}}
+	if !reflect.DeepEqual(table.Data, expect) {
+		t.Errorf("Data = %v, expect %v", table.Data, expect)
+	}
+}
+
+func TestXlsxColumnIndex(t *testing.T) {
+	for ref, expect := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "ab3": 27} {
+		index, err := xlsxColumnIndex(ref)
+		if err != nil || index != expect {
+			t.Errorf("xlsxColumnIndex(%q) = %v, %v, expect %v", ref, index, err, expect)
+		}
+	}
+	if _, err := xlsxColumnIndex("12"); err == nil {
+		t.Errorf("xlsxColumnIndex(12) expect error")
+	}
+}
+
+// 样式 1 为内置日期格式，2 为自定义日期时间格式，3 的 d 在引号中不是日期，4 为只有时间的内置格式
+const testXlsxStylesXml = `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy/mm/dd\ hh:mm"/><numFmt numFmtId="165" formatCode="0.00&quot;d&quot;"/></numFmts>
+<cellXfs count="5"><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/><xf numFmtId="165"/><xf numFmtId="20"/></cellXfs>
+</styleSheet>`
+
+const testXlsxDateSheetXml = `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+<dimension ref="A1:E6"/>
+<sheetData>
+<row r="3"><c r="A3" t="inlineStr"><is><t>server</t></is></c><c r="B3" t="inlineStr"><is><t>server</t></is></c><c r="C3" t="inlineStr"><is><t>server</t></is></c><c r="D3" t="inlineStr"><is><t>server</t></is></c><c r="E3" t="inlineStr"><is><t>server</t></is></c></row>
+<row r="4"><c r="A4" t="inlineStr"><is><t>id</t></is></c><c r="B4" t="inlineStr"><is><t>open</t></is></c><c r="C4" t="inlineStr"><is><t>close</t></is></c><c r="D4" t="inlineStr"><is><t>rate</t></is></c><c r="E4" t="inlineStr"><is><t>ratio</t></is></c></row>
+<row r="5"><c r="A5" t="inlineStr"><is><t>int</t></is></c><c r="B5" t="inlineStr"><is><t>datetime</t></is></c><c r="C5" t="inlineStr"><is><t>datetime</t></is></c><c r="D5" t="inlineStr"><is><t>float</t></is></c><c r="E5" t="inlineStr"><is><t>float</t></is></c></row>
+<row r="6"><c r="A6"><v>1001</v></c><c r="B6" s="1"><v>45658</v></c><c r="C6" s="2" t="n"><v>45658.5</v></c><c r="D6" s="3"><v>3</v></c><c r="E6" s="4"><v>0.5</v></c></row>
+</sheetData>
+</worksheet>`
+
+func TestXlsxDateCell(t *testing.T) {
+	content := newTestXlsxZip(t, map[string]string{
+		xlsxWorkbookPath:           testXlsxWorkbookXml,
+		xlsxWorkbookRelsPath:       testXlsxWorkbookRelsXml,
+		xlsxStylesPath:             testXlsxStylesXml,
+		"xl/worksheets/sheet1.xml": testXlsxDateSheetXml,
+	})
+	workbook, err := NewXlsxWorkbook(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sheetReader, err := workbook.SheetReader("Item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sheetReader.Close()
+
+	loader, err := NewTableLoader("Item", sheetReader, DefaultHeaderLayout, EXPORT_SERVER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := loader.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := [][]interface{}{{
+		int64(1001),
+		time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+		float64(3),
+		float64(0.5),
+	}}
+	if len(table.Data) != 1 || len(table.Data[0]) != len(expect[0]) {
+		t.Fatalf("Data = %v, expect %v", table.Data, expect)
+	}
+	for i, v := range table.Data[0] {
+		if tm, ok := v.(time.Time); ok {
+			if !tm.Equal(expect[0][i].(time.Time)) {
+				t.Errorf("column %v = %v, expect %v", i, tm, expect[0][i])
+			}
+		} else if v != expect[0][i] {
+			t.Errorf("column %v = %v, expect %v", i, v, expect[0][i])
+		}
+	}
+}
+
+func TestXlsxSerialToDate(t *testing.T) {
+	for _, c := range []struct {
+		v        string
+		date1904 bool
+		expect   string
+		ok       bool
+	}{
+		{"1", false, "1900-01-01", true},
+		{"59", false, "1900-02-28", true},
+		{"61", false, "1900-03-01", true},
+		{"45658", false, "2025-01-01", true},
+		{"45658.75", false, "2025-01-01 18:00:00", true},
+		{"0", true, "1904-01-01", true},
+		{"44196", true, "2025-01-01", true},
+		{"abc", false, "", false},
+		{"-1", false, "", false},
+	} {
+		date, ok := xlsxSerialToDate(c.v, c.date1904)
+		if date != c.expect || ok != c.ok {
+			t.Errorf("xlsxSerialToDate(%v, %v) = %q, %v, expect %q, %v", c.v, c.date1904, date, ok, c.expect, c.ok)
+		}
+	}
+}
+
+func TestIsXlsxDateFormatCode(t *testing.T) {
+	for formatCode, expect := range map[string]bool{
+		"yyyy-mm-dd":             true,
+		"m/d/yy h:mm":            true,
+		`[$-804]yyyy"年"m"月"d"日"`: true,
+		"h:mm:ss":                false,
+		"[h]:mm":                 false,
+		`0.00"d"`:                false,
+		`#,##0\ "days"`:          false,
+		"General":                false,
+	} {
+		if isXlsxDateFormatCode(formatCode) != expect {
+			t.Errorf("isXlsxDateFormatCode(%q) expect %v", formatCode, expect)
+		}
+	}
+}